@@ -0,0 +1,274 @@
+package flux
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CloudEventsSpecVersion is the version of the CloudEvents specification implemented by CloudEventsCodec.
+const CloudEventsSpecVersion = "1.0"
+
+// CloudEventsContentType is the media type of an event encoded in the structured content mode of CloudEvents.
+const CloudEventsContentType = "application/cloudevents+json"
+
+// DefaultCloudEventsSource is the source attribute of the events encoded by a CloudEventsCodec without a source.
+const DefaultCloudEventsSource = "flux"
+
+// ErrInvalidCloudEvent is an error that is returned when a message cannot be decoded as a CloudEvent.
+var ErrInvalidCloudEvent = errors.New("invalid cloud event")
+
+// CloudEventsMode is the content mode in which a CloudEventsCodec encodes messages.
+type CloudEventsMode int
+
+const (
+	// CloudEventsStructured encodes the whole event, attributes and payload, as the body of a message.
+	CloudEventsStructured CloudEventsMode = iota
+
+	// CloudEventsBinary encodes the event's attributes as message headers, and its payload as the body of the
+	// message. It only applies to transports which support headers; elsewhere, events are encoded in structured mode.
+	CloudEventsBinary
+)
+
+// Compile-time assertion that CloudEventsCodec implements the HeaderCodec interface.
+var _ HeaderCodec = (*CloudEventsCodec)(nil)
+
+// NewCloudEventsCodec returns a new codec which encodes events as CloudEvents, in the JSON event format.
+//
+// The event's ID, Topic, Key and Timestamp map onto the id, type, subject and time attributes. Its Sequence and
+// TraceParent are carried by the sequence and traceparent extension attributes.
+func NewCloudEventsCodec(opts ...CloudEventsCodecOption) *CloudEventsCodec {
+	config := CloudEventsCodecConfig{
+		Source: DefaultCloudEventsSource,
+		Mode:   CloudEventsStructured,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &CloudEventsCodec{config: &config}
+}
+
+// CloudEventsCodec is a Codec which encodes events as CloudEvents.
+type CloudEventsCodec struct {
+	config *CloudEventsCodecConfig
+}
+
+// CloudEventsCodecConfig holds the configuration of a CloudEventsCodec.
+type CloudEventsCodecConfig struct {
+	// The source attribute of every encoded event, which identifies the context in which events are created.
+	Source string
+
+	// The content mode in which messages are encoded by EncodeMessage.
+	Mode CloudEventsMode
+}
+
+// CloudEventsCodecOption is an interface that allows for functional options to be applied to a CloudEventsCodecConfig.
+type CloudEventsCodecOption interface {
+	Apply(*CloudEventsCodecConfig)
+}
+
+// CloudEventsCodecOptionFunc is a function type that implements the CloudEventsCodecOption interface.
+type CloudEventsCodecOptionFunc func(*CloudEventsCodecConfig)
+
+// Apply applies the function to the codec config.
+func (f CloudEventsCodecOptionFunc) Apply(config *CloudEventsCodecConfig) {
+	f(config)
+}
+
+// WithCloudEventsSource sets the source attribute of the events encoded by the codec.
+func WithCloudEventsSource(source string) CloudEventsCodecOption {
+	return CloudEventsCodecOptionFunc(func(config *CloudEventsCodecConfig) {
+		config.Source = source
+	})
+}
+
+// WithCloudEventsMode sets the content mode in which the codec encodes messages for transports which support headers.
+func WithCloudEventsMode(mode CloudEventsMode) CloudEventsCodecOption {
+	return CloudEventsCodecOptionFunc(func(config *CloudEventsCodecConfig) {
+		config.Mode = mode
+	})
+}
+
+// cloudEvent is the JSON event format of a CloudEvent. Extension attributes are encoded as strings.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Sequence        string          `json:"sequence,omitempty"`
+	TraceParent     string          `json:"traceparent,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      []byte          `json:"data_base64,omitempty"`
+}
+
+// Encode encodes the event in structured mode.
+func (codec *CloudEventsCodec) Encode(e Event) ([]byte, error) {
+	ce := cloudEvent{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              e.ID(),
+		Source:          codec.config.Source,
+		Type:            e.Topic().String(),
+		Subject:         e.Key(),
+		Time:            e.Timestamp().Format(time.RFC3339Nano),
+		DataContentType: e.ContentType(),
+		Sequence:        strconv.FormatUint(uint64(e.Sequence()), 10),
+		TraceParent:     e.TraceParent(),
+	}
+
+	// JSON payloads are embedded in the event, so their formatting may change. Anything else is base64 encoded.
+	if len(e.Payload()) > 0 {
+		if isJSONContentType(e.ContentType()) && json.Valid(e.Payload()) {
+			ce.Data = e.Payload()
+		} else {
+			ce.DataBase64 = e.Payload()
+		}
+	}
+
+	return json.Marshal(&ce)
+}
+
+// Decode decodes an event encoded in structured mode.
+func (codec *CloudEventsCodec) Decode(data []byte) (Event, error) {
+	var ce cloudEvent
+	if err := json.Unmarshal(data, &ce); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+
+	e, err := newCloudEvent(ce.SpecVersion, ce.ID, ce.Type, ce.Subject, ce.Time, ce.Sequence)
+	if err != nil {
+		return nil, err
+	}
+
+	e.contentType = ce.DataContentType
+	e.traceParent = ce.TraceParent
+
+	switch {
+	case len(ce.DataBase64) > 0:
+		e.payload = ce.DataBase64
+	case len(ce.Data) > 0:
+		e.payload = []byte(ce.Data)
+	}
+
+	return e, nil
+}
+
+// Headers of a message encoded in binary mode. The traceparent extension is carried by the W3C traceparent header.
+const (
+	cloudEventsHeaderPrefix = "ce-"
+	cloudEventsContentType  = "content-type"
+	cloudEventsTraceParent  = "traceparent"
+)
+
+// EncodeMessage encodes the event in the codec's content mode.
+func (codec *CloudEventsCodec) EncodeMessage(e Event, header MessageHeader) ([]byte, error) {
+	if codec.config.Mode != CloudEventsBinary {
+		header.Set(cloudEventsContentType, CloudEventsContentType)
+		return codec.Encode(e)
+	}
+
+	header.Set(cloudEventsHeaderPrefix+"specversion", CloudEventsSpecVersion)
+	header.Set(cloudEventsHeaderPrefix+"id", e.ID())
+	header.Set(cloudEventsHeaderPrefix+"source", codec.config.Source)
+	header.Set(cloudEventsHeaderPrefix+"type", e.Topic().String())
+	header.Set(cloudEventsHeaderPrefix+"time", e.Timestamp().Format(time.RFC3339Nano))
+	header.Set(cloudEventsHeaderPrefix+"sequence", strconv.FormatUint(uint64(e.Sequence()), 10))
+
+	if e.Key() != "" {
+		header.Set(cloudEventsHeaderPrefix+"subject", e.Key())
+	}
+
+	if e.ContentType() != "" {
+		header.Set(cloudEventsContentType, e.ContentType())
+	}
+
+	if e.TraceParent() != "" {
+		header.Set(cloudEventsTraceParent, e.TraceParent())
+	}
+
+	return e.Payload(), nil
+}
+
+// DecodeMessage decodes an event from a message in either content mode, which is detected from its content type.
+func (codec *CloudEventsCodec) DecodeMessage(header MessageHeader, body []byte) (Event, error) {
+	if mediaType, _, _ := mime.ParseMediaType(header.Get(cloudEventsContentType)); mediaType == CloudEventsContentType {
+		return codec.Decode(body)
+	}
+
+	e, err := newCloudEvent(
+		header.Get(cloudEventsHeaderPrefix+"specversion"),
+		header.Get(cloudEventsHeaderPrefix+"id"),
+		header.Get(cloudEventsHeaderPrefix+"type"),
+		header.Get(cloudEventsHeaderPrefix+"subject"),
+		header.Get(cloudEventsHeaderPrefix+"time"),
+		header.Get(cloudEventsHeaderPrefix+"sequence"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	e.contentType = header.Get(cloudEventsContentType)
+	e.traceParent = header.Get(cloudEventsTraceParent)
+
+	if len(body) > 0 {
+		e.payload = body
+	}
+
+	return e, nil
+}
+
+// newCloudEvent returns an event with the attributes of a CloudEvent, validating those which are required.
+func newCloudEvent(specVersion, id, typ, subject, timestamp, sequence string) (*defaultEvent, error) {
+	if specVersion != CloudEventsSpecVersion {
+		return nil, fmt.Errorf("%w: unsupported specversion %q", ErrInvalidCloudEvent, specVersion)
+	}
+
+	if id == "" || typ == "" {
+		return nil, fmt.Errorf("%w: missing id or type", ErrInvalidCloudEvent)
+	}
+
+	e := defaultEvent{
+		id:    id,
+		topic: EventTopic(typ),
+		key:   subject,
+	}
+
+	if timestamp != "" {
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid time: %w", ErrInvalidCloudEvent, err)
+		}
+
+		e.timestamp = t
+	}
+
+	// Events produced elsewhere may not have a sequence.
+	if sequence != "" {
+		seq, err := strconv.ParseUint(sequence, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid sequence: %w", ErrInvalidCloudEvent, err)
+		}
+
+		e.sequence = uint(seq)
+	}
+
+	return &e, nil
+}
+
+// isJSONContentType returns true if the media type is JSON, or a structured syntax suffixed with +json.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,251 @@
+package flux_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloudEventsCodec(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	withPayload := func(contentType string, payload []byte) *testEvent {
+		e := generator.generateEvent("test.topic", "test-key")
+		e.contentType = contentType
+		e.payload = payload
+		e.traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		return e
+	}
+
+	events := map[string]*testEvent{
+		"without payload": generator.generateEvent("test.topic", "test-key"),
+		"json payload":    withPayload("application/json", []byte(`{"name":"test"}`)),
+		"binary payload":  withPayload("application/octet-stream", []byte{0, 1, 2, 3}),
+		"invalid json":    withPayload("application/json", []byte(`{"name":`)),
+	}
+
+	t.Run("structured mode", func(t *testing.T) {
+		codec := flux.NewCloudEventsCodec(flux.WithCloudEventsSource("/test"))
+
+		for name, e := range events {
+			t.Run(name, func(t *testing.T) {
+				data, err := codec.Encode(e)
+				require.NoError(t, err)
+
+				got, err := codec.Decode(data)
+				require.NoError(t, err)
+				requireDecodedEventEqual(t, e, got)
+			})
+		}
+
+		t.Run("attributes", func(t *testing.T) {
+			e := events["json payload"]
+
+			data, err := codec.Encode(e)
+			require.NoError(t, err)
+
+			var attrs map[string]any
+			require.NoError(t, json.Unmarshal(data, &attrs))
+
+			require.Equal(t, "1.0", attrs["specversion"])
+			require.Equal(t, e.ID(), attrs["id"])
+			require.Equal(t, "/test", attrs["source"])
+			require.Equal(t, "test.topic", attrs["type"])
+			require.Equal(t, "test-key", attrs["subject"])
+			require.Equal(t, e.Timestamp().Format(time.RFC3339Nano), attrs["time"])
+			require.Equal(t, "application/json", attrs["datacontenttype"])
+			require.Equal(t, strconv.FormatUint(uint64(e.Sequence()), 10), attrs["sequence"])
+			require.Equal(t, e.TraceParent(), attrs["traceparent"])
+			require.Equal(t, map[string]any{"name": "test"}, attrs["data"])
+			require.NotContains(t, attrs, "data_base64")
+		})
+
+		t.Run("binary payloads are base64 encoded", func(t *testing.T) {
+			data, err := codec.Encode(events["binary payload"])
+			require.NoError(t, err)
+
+			var attrs map[string]any
+			require.NoError(t, json.Unmarshal(data, &attrs))
+			require.Equal(t, "AAECAw==", attrs["data_base64"])
+			require.NotContains(t, attrs, "data")
+		})
+
+		t.Run("events from other producers", func(t *testing.T) {
+			data := []byte(`{
+				"specversion": "1.0",
+				"id": "A234-1234-1234",
+				"source": "https://github.com/cloudevents/spec/pull",
+				"type": "com.github.pull_request.opened",
+				"time": "2018-04-05T17:31:00Z",
+				"datacontenttype": "text/xml",
+				"data": "<much wow=\"xml\"/>"
+			}`)
+
+			e, err := codec.Decode(data)
+			require.NoError(t, err)
+			require.Equal(t, "A234-1234-1234", e.ID())
+			require.Equal(t, flux.EventTopic("com.github.pull_request.opened"), e.Topic())
+			require.Zero(t, e.Sequence())
+			require.Equal(t, time.Date(2018, 4, 5, 17, 31, 0, 0, time.UTC), e.Timestamp().UTC())
+			require.Equal(t, "text/xml", e.ContentType())
+			require.Equal(t, []byte(`"<much wow=\"xml\"/>"`), e.Payload())
+		})
+
+		t.Run("invalid events", func(t *testing.T) {
+			tests := map[string]string{
+				"specversion": `{"specversion":"0.3","id":"1","source":"/test","type":"test"}`,
+				"missing id":  `{"specversion":"1.0","source":"/test","type":"test"}`,
+				"time":        `{"specversion":"1.0","id":"1","source":"/test","type":"test","time":"yesterday"}`,
+				"sequence":    `{"specversion":"1.0","id":"1","source":"/test","type":"test","sequence":"first"}`,
+			}
+
+			for name, data := range tests {
+				t.Run(name, func(t *testing.T) {
+					_, err := codec.Decode([]byte(data))
+					require.ErrorIs(t, err, flux.ErrInvalidCloudEvent)
+				})
+			}
+		})
+	})
+
+	t.Run("binary mode", func(t *testing.T) {
+		codec := flux.NewCloudEventsCodec(flux.WithCloudEventsMode(flux.CloudEventsBinary))
+
+		headers := map[string]func() flux.MessageHeader{
+			"http": func() flux.MessageHeader { return http.Header{} },
+			"nats": func() flux.MessageHeader { return nats.Header{} },
+		}
+
+		for name, newHeader := range headers {
+			t.Run(name, func(t *testing.T) {
+				for name, e := range events {
+					t.Run(name, func(t *testing.T) {
+						header := newHeader()
+
+						body, err := codec.EncodeMessage(e, header)
+						require.NoError(t, err)
+						require.Equal(t, e.Payload(), body)
+						require.Equal(t, e.ID(), header.Get("ce-id"))
+						require.Equal(t, "flux", header.Get("ce-source"))
+
+						got, err := codec.DecodeMessage(header, body)
+						require.NoError(t, err)
+						requireDecodedEventEqual(t, e, got)
+					})
+				}
+			})
+		}
+
+		t.Run("structured messages are detected", func(t *testing.T) {
+			e := events["json payload"]
+
+			structured := flux.NewCloudEventsCodec()
+			header := http.Header{}
+
+			body, err := structured.EncodeMessage(e, header)
+			require.NoError(t, err)
+			require.Equal(t, flux.CloudEventsContentType, header.Get("Content-Type"))
+
+			got, err := codec.DecodeMessage(header, body)
+			require.NoError(t, err)
+			requireDecodedEventEqual(t, e, got)
+		})
+	})
+}
+
+func TestCloudEventsCodec_Transports(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	modes := map[string]flux.CloudEventsMode{
+		"structured": flux.CloudEventsStructured,
+		"binary":     flux.CloudEventsBinary,
+	}
+
+	for name, mode := range modes {
+		codec := flux.NewCloudEventsCodec(flux.WithCloudEventsMode(mode))
+
+		t.Run(name, func(t *testing.T) {
+			t.Run("webhook", func(t *testing.T) {
+				received := make(chan flux.Event, 1)
+				handler := func(ctx context.Context, e flux.Event) error {
+					received <- e
+					return nil
+				}
+
+				receiver := flux.NewWebhookReceiver(codec, []byte("secret"), handler)
+
+				server := httptest.NewServer(receiver)
+				defer server.Close()
+
+				e := generator.generateEvent("test.topic", "test-key")
+				e.payload = []byte(`{"name":"test"}`)
+				e.contentType = "application/json"
+
+				dispatcher := flux.NewWebhookDispatcher(server.URL, codec, []byte("secret"))
+				require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+
+				requireDecodedEventEqual(t, e, <-received)
+			})
+
+			t.Run("webhook rejects tampered attributes", func(t *testing.T) {
+				handler := func(ctx context.Context, e flux.Event) error {
+					t.Error("handler should not be called")
+					return nil
+				}
+
+				receiver := flux.NewWebhookReceiver(codec, []byte("secret"), handler)
+
+				// Change the type of the event in transit, which is carried by a header in binary mode.
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					r.Header.Set("Ce-Type", "other.topic")
+					receiver.ServeHTTP(w, r)
+				}))
+				defer server.Close()
+
+				dispatcher := flux.NewWebhookDispatcher(
+					server.URL,
+					codec,
+					[]byte("secret"),
+					flux.WithWebhookRetries(0, 0),
+				)
+
+				err := dispatcher.Dispatch(context.Background(), generator.generateEvent("test.topic", "test-key"))
+				require.ErrorContains(t, err, strconv.Itoa(http.StatusUnauthorized))
+			})
+
+			t.Run("nats", func(t *testing.T) {
+				conn := setupNats(t)
+
+				sub, err := conn.SubscribeSync("test.topic")
+				require.NoError(t, err)
+
+				e := generator.generateEvent("test.topic", "test-key")
+				e.payload = []byte("test")
+				e.contentType = "text/plain"
+
+				dispatcher := flux.NewNatsDispatcher(conn, codec)
+				require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+
+				msg, err := sub.NextMsg(5 * time.Second)
+				require.NoError(t, err)
+
+				if mode == flux.CloudEventsBinary {
+					require.Equal(t, e.Payload(), msg.Data)
+				}
+
+				got, err := codec.DecodeMessage(msg.Header, msg.Data)
+				require.NoError(t, err)
+				requireDecodedEventEqual(t, e, got)
+			})
+		})
+	}
+}
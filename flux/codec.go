@@ -16,6 +16,44 @@ type Codec interface {
 	Decode(data []byte) (Event, error)
 }
 
+// MessageHeader is the set of headers of a message, such as an http.Header or a nats.Header.
+type MessageHeader interface {
+	Get(key string) string
+	Set(key, value string)
+}
+
+// HeaderCodec is implemented by codecs which encode some of an event's attributes as message headers, for transports
+// which support them. The NATS and webhook dispatchers use it in place of Encode when their codec implements it.
+type HeaderCodec interface {
+	Codec
+
+	// EncodeMessage encodes the event as the body of a message, and sets the message's headers.
+	EncodeMessage(e Event, header MessageHeader) ([]byte, error)
+
+	// DecodeMessage decodes an event from the headers and body of a message.
+	DecodeMessage(header MessageHeader, body []byte) (Event, error)
+}
+
+// encodeMessage encodes the event as the body of a message, using EncodeMessage to also set the message's headers if
+// the codec is a HeaderCodec.
+func encodeMessage(codec Codec, e Event, header MessageHeader) ([]byte, error) {
+	if hc, ok := codec.(HeaderCodec); ok {
+		return hc.EncodeMessage(e, header)
+	}
+
+	return codec.Encode(e)
+}
+
+// decodeMessage decodes an event from the body of a message, using DecodeMessage to also read the message's headers if
+// the codec is a HeaderCodec.
+func decodeMessage(codec Codec, header MessageHeader, body []byte) (Event, error) {
+	if hc, ok := codec.(HeaderCodec); ok {
+		return hc.DecodeMessage(header, body)
+	}
+
+	return codec.Decode(body)
+}
+
 // NewJSONCodec returns a new JSON codec.
 func NewJSONCodec() Codec {
 	return &JSONCodec{}
@@ -24,20 +62,26 @@ func NewJSONCodec() Codec {
 type JSONCodec struct{}
 
 type jsonEvent struct {
-	ID        string     `json:"id"`
-	Topic     EventTopic `json:"topic"`
-	Sequence  uint       `json:"sequence"`
-	Key       string     `json:"key"`
-	Timestamp time.Time  `json:"timestamp"`
+	ID          string     `json:"id"`
+	Topic       EventTopic `json:"topic"`
+	Sequence    uint       `json:"sequence"`
+	Key         string     `json:"key"`
+	Timestamp   time.Time  `json:"timestamp"`
+	Payload     []byte     `json:"payload,omitempty"`
+	ContentType string     `json:"content_type,omitempty"`
+	TraceParent string     `json:"trace_parent,omitempty"`
 }
 
 func (codec *JSONCodec) Encode(e Event) ([]byte, error) {
 	jsonEvent := jsonEvent{
-		ID:        e.ID(),
-		Topic:     e.Topic(),
-		Sequence:  e.Sequence(),
-		Key:       e.Key(),
-		Timestamp: e.Timestamp(),
+		ID:          e.ID(),
+		Topic:       e.Topic(),
+		Sequence:    e.Sequence(),
+		Key:         e.Key(),
+		Timestamp:   e.Timestamp(),
+		Payload:     e.Payload(),
+		ContentType: e.ContentType(),
+		TraceParent: e.TraceParent(),
 	}
 
 	return json.Marshal(&jsonEvent)
@@ -51,11 +95,14 @@ func (codec *JSONCodec) Decode(data []byte) (Event, error) {
 	}
 
 	e := defaultEvent{
-		id:        jsonEvent.ID,
-		topic:     jsonEvent.Topic,
-		sequence:  jsonEvent.Sequence,
-		key:       jsonEvent.Key,
-		timestamp: jsonEvent.Timestamp,
+		id:          jsonEvent.ID,
+		topic:       jsonEvent.Topic,
+		sequence:    jsonEvent.Sequence,
+		key:         jsonEvent.Key,
+		timestamp:   jsonEvent.Timestamp,
+		payload:     jsonEvent.Payload,
+		contentType: jsonEvent.ContentType,
+		traceParent: jsonEvent.TraceParent,
 	}
 
 	return &e, nil
@@ -81,22 +128,50 @@ func (codec *ProtobufCodec) Decode(data []byte) (Event, error) {
 	return EventFromProto(&pb), nil
 }
 
+// EventFromProto returns the event described by the protobuf message, which is a *DeadLetter if the message has the
+// dead-letter fields set.
 func EventFromProto(pb *fluxpb.Event) Event {
-	return &defaultEvent{
-		id:        pb.Id,
-		topic:     EventTopic(pb.Topic),
-		sequence:  uint(pb.Sequence),
-		key:       pb.Key,
-		timestamp: pb.Timestamp.AsTime(),
+	e := &defaultEvent{
+		id:          pb.Id,
+		topic:       EventTopic(pb.Topic),
+		sequence:    uint(pb.Sequence),
+		key:         pb.Key,
+		timestamp:   pb.Timestamp.AsTime(),
+		payload:     pb.Payload,
+		contentType: pb.ContentType,
+		traceParent: pb.TraceParent,
+	}
+
+	if pb.DeadLetterAttempts == 0 {
+		return e
+	}
+
+	return &DeadLetter{
+		Event:          e,
+		Cause:          pb.DeadLetterCause,
+		Attempts:       int(pb.DeadLetterAttempts),
+		DeadLetteredAt: pb.DeadLetteredAt.AsTime(),
 	}
 }
 
+// EventToProto returns the protobuf message describing the event, including the details of a *DeadLetter.
 func EventToProto(e Event) *fluxpb.Event {
-	return &fluxpb.Event{
-		Id:        e.ID(),
-		Topic:     string(e.Topic()),
-		Sequence:  uint64(e.Sequence()),
-		Key:       e.Key(),
-		Timestamp: timestamppb.New(e.Timestamp()),
+	pb := &fluxpb.Event{
+		Id:          e.ID(),
+		Topic:       string(e.Topic()),
+		Sequence:    uint64(e.Sequence()),
+		Key:         e.Key(),
+		Timestamp:   timestamppb.New(e.Timestamp()),
+		Payload:     e.Payload(),
+		ContentType: e.ContentType(),
+		TraceParent: e.TraceParent(),
 	}
+
+	if letter, ok := e.(*DeadLetter); ok {
+		pb.DeadLetterCause = letter.Cause
+		pb.DeadLetterAttempts = uint32(letter.Attempts)
+		pb.DeadLetteredAt = timestamppb.New(letter.DeadLetteredAt)
+	}
+
+	return pb
 }
@@ -10,6 +10,20 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// requireDecodedEventEqual asserts that an event survived being encoded and decoded by a codec.
+func requireDecodedEventEqual(t *testing.T, expected, actual flux.Event) {
+	t.Helper()
+
+	require.Equal(t, expected.ID(), actual.ID())
+	require.Equal(t, expected.Topic(), actual.Topic())
+	require.Equal(t, expected.Sequence(), actual.Sequence())
+	require.Equal(t, expected.Key(), actual.Key())
+	require.True(t, expected.Timestamp().Equal(actual.Timestamp()))
+	require.Equal(t, expected.Payload(), actual.Payload())
+	require.Equal(t, expected.ContentType(), actual.ContentType())
+	require.Equal(t, expected.TraceParent(), actual.TraceParent())
+}
+
 func TestCodec(t *testing.T) {
 	generator := NewEventGenerator(t)
 
@@ -59,3 +73,47 @@ func TestCodec(t *testing.T) {
 		})
 	})
 }
+
+func TestCodec_Payload(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	event := generator.generateEvent("test-topic", uuid.NewString())
+	event.payload = []byte(`{"name":"test"}`)
+	event.contentType = "application/json"
+	event.traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	codecs := map[string]flux.Codec{
+		"json codec":     flux.NewJSONCodec(),
+		"protobuf codec": flux.NewProtobufCodec(),
+	}
+
+	for name, codec := range codecs {
+		t.Run(name, func(t *testing.T) {
+			data, err := codec.Encode(event)
+			require.NoError(t, err)
+
+			e, err := codec.Decode(data)
+			require.NoError(t, err)
+
+			require.Equal(t, event.Payload(), e.Payload())
+			require.Equal(t, event.ContentType(), e.ContentType())
+			require.Equal(t, event.TraceParent(), e.TraceParent())
+		})
+	}
+
+	t.Run("without payload", func(t *testing.T) {
+		for name, codec := range codecs {
+			t.Run(name, func(t *testing.T) {
+				data, err := codec.Encode(generator.generateRandomEvent())
+				require.NoError(t, err)
+
+				e, err := codec.Decode(data)
+				require.NoError(t, err)
+
+				require.Empty(t, e.Payload())
+				require.Empty(t, e.ContentType())
+				require.Empty(t, e.TraceParent())
+			})
+		}
+	})
+}
@@ -0,0 +1,269 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DispatchError is an error returned by composite dispatchers, which identifies the child dispatcher that failed.
+type DispatchError struct {
+	// The name of the child dispatcher.
+	Dispatcher string
+
+	// The error returned by the child dispatcher.
+	Err error
+}
+
+func (e *DispatchError) Error() string {
+	return fmt.Sprintf("dispatcher %q: %v", e.Dispatcher, e.Err)
+}
+
+func (e *DispatchError) Unwrap() error {
+	return e.Err
+}
+
+// FanOutPolicy determines how a FanOutDispatcher handles failures of its children.
+type FanOutPolicy int
+
+const (
+	// FanOutAllOrNothing only considers an event dispatched once every child has dispatched it. If any child fails, an
+	// error is returned so that the event is dispatched again. Children which have already dispatched the event are
+	// skipped when it is retried.
+	FanOutAllOrNothing FanOutPolicy = iota
+
+	// FanOutBestEffort considers an event dispatched once every child has attempted to dispatch it, regardless of
+	// whether they succeeded. Failures are only reported to the dispatcher's error function.
+	FanOutBestEffort
+)
+
+// DefaultFanOutRetryWindow is the number of sequences behind the latest dispatched event for which a FanOutDispatcher
+// remembers partial deliveries, unless it is configured with a different window.
+const DefaultFanOutRetryWindow = 10000
+
+// Compile-time assertion that FanOutDispatcher implements the Dispatcher interface.
+var _ Dispatcher = (*FanOutDispatcher)(nil)
+
+// NewFanOutDispatcher returns a dispatcher which dispatches every event to each of the named children, concurrently.
+func NewFanOutDispatcher(
+	policy FanOutPolicy,
+	children map[string]Dispatcher,
+	opts ...FanOutDispatcherOption,
+) *FanOutDispatcher {
+	config := FanOutDispatcherConfig{
+		RetryWindow: DefaultFanOutRetryWindow,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	names := make([]string, 0, len(children))
+	for name := range children {
+		names = append(names, name)
+	}
+
+	// Sort the names so that errors are reported in a consistent order.
+	sort.Strings(names)
+
+	return &FanOutDispatcher{
+		policy:    policy,
+		children:  children,
+		names:     names,
+		config:    config,
+		delivered: make(map[string]*fanOutDelivery),
+	}
+}
+
+// FanOutDispatcher is a dispatcher that delivers each event to several child dispatchers, so that a single Relay can
+// feed multiple destinations.
+type FanOutDispatcher struct {
+	policy   FanOutPolicy
+	children map[string]Dispatcher
+	names    []string
+	config   FanOutDispatcherConfig
+
+	// The children which have already dispatched each event that is being retried, by event ID. Events are removed
+	// once every child has dispatched them, or once they fall outside of the retry window. It is guarded by mu, as
+	// events may be dispatched concurrently.
+	mu        sync.Mutex
+	delivered map[string]*fanOutDelivery
+
+	// The highest sequence dispatched so far, which the retry window trails.
+	latest uint
+}
+
+// fanOutDelivery records the children which have dispatched an event that is being retried.
+type fanOutDelivery struct {
+	sequence uint
+	children map[string]bool
+}
+
+type FanOutDispatcherConfig struct {
+	// If set, called whenever a child fails to dispatch an event.
+	OnError func(name string, e Event, err error)
+
+	// The number of sequences behind the latest dispatched event for which partial deliveries are remembered. Events
+	// which a relay dead-letters or skips are never retried, so their deliveries are forgotten once they fall this
+	// far behind. An event which is retried after that is dispatched to every child again.
+	RetryWindow uint
+}
+
+// FanOutDispatcherOption is an interface that allows for functional options to be applied to a
+// FanOutDispatcherConfig.
+type FanOutDispatcherOption interface {
+	Apply(*FanOutDispatcherConfig)
+}
+
+// FanOutDispatcherOptionFunc is a function type that implements the FanOutDispatcherOption interface.
+type FanOutDispatcherOptionFunc func(*FanOutDispatcherConfig)
+
+// Apply applies the function to the dispatcher.
+func (f FanOutDispatcherOptionFunc) Apply(config *FanOutDispatcherConfig) {
+	f(config)
+}
+
+// WithFanOutErrorFunc sets a function which is called whenever a child fails to dispatch an event.
+func WithFanOutErrorFunc(fn func(name string, e Event, err error)) FanOutDispatcherOption {
+	return FanOutDispatcherOptionFunc(func(config *FanOutDispatcherConfig) {
+		config.OnError = fn
+	})
+}
+
+// WithFanOutRetryWindow sets the number of sequences behind the latest dispatched event for which partial deliveries
+// are remembered. It should be at least the buffer size of the relay that uses the dispatcher.
+func WithFanOutRetryWindow(window uint) FanOutDispatcherOption {
+	return FanOutDispatcherOptionFunc(func(config *FanOutDispatcherConfig) {
+		config.RetryWindow = window
+	})
+}
+
+// Dispatch dispatches the event to every child. With FanOutAllOrNothing, the returned error joins a DispatchError for
+// each child that failed. It is safe for concurrent use, so it may be used by a relay with several partitions.
+func (d *FanOutDispatcher) Dispatch(ctx context.Context, e Event) error {
+	// Skip the children which dispatched the event on a previous attempt.
+	d.mu.Lock()
+	d.forgetBefore(e.Sequence())
+
+	pending := make([]bool, len(d.names))
+	for i, name := range d.names {
+		pending[i] = d.delivered[e.ID()] == nil || !d.delivered[e.ID()].children[name]
+	}
+	d.mu.Unlock()
+
+	errs := make([]error, len(d.names))
+
+	var wg sync.WaitGroup
+	for i, name := range d.names {
+		if !pending[i] {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+
+			if err := d.children[name].Dispatch(ctx, e); err != nil {
+				errs[i] = &DispatchError{Dispatcher: name, Err: err}
+			}
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	if d.config.OnError != nil {
+		for i, name := range d.names {
+			if errs[i] != nil {
+				d.config.OnError(name, e, errors.Unwrap(errs[i]))
+			}
+		}
+	}
+
+	err := errors.Join(errs...)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Only remember deliveries for events which will be retried.
+	if err == nil || d.policy == FanOutBestEffort {
+		delete(d.delivered, e.ID())
+		return nil
+	}
+
+	if d.delivered[e.ID()] == nil {
+		d.delivered[e.ID()] = &fanOutDelivery{sequence: e.Sequence(), children: make(map[string]bool)}
+	}
+
+	for i, name := range d.names {
+		if errs[i] == nil {
+			d.delivered[e.ID()].children[name] = true
+		}
+	}
+
+	return err
+}
+
+// forgetBefore advances the retry window to the sequence, if it is the highest seen so far, and forgets the deliveries
+// of events which have fallen outside of it. It must be called with mu held.
+func (d *FanOutDispatcher) forgetBefore(sequence uint) {
+	if sequence <= d.latest {
+		return
+	}
+
+	d.latest = sequence
+
+	for id, delivery := range d.delivered {
+		if delivery.sequence+d.config.RetryWindow < d.latest {
+			delete(d.delivered, id)
+		}
+	}
+}
+
+// Route directs the events which match its filter to a dispatcher.
+type Route struct {
+	// The name of the route, which is used to identify it in errors.
+	Name string
+
+	// Determines which events are sent to the route's dispatcher. A nil filter matches every event.
+	Filter EventFilter
+
+	// The dispatcher that matching events are sent to.
+	Dispatcher Dispatcher
+}
+
+// Compile-time assertion that RouterDispatcher implements the Dispatcher interface.
+var _ Dispatcher = (*RouterDispatcher)(nil)
+
+// NewRouterDispatcher returns a dispatcher which sends each event to the first of the given routes that matches it.
+//
+// Events which do not match any route are dropped. To send them elsewhere instead, add a final route without a filter.
+func NewRouterDispatcher(routes ...Route) *RouterDispatcher {
+	return &RouterDispatcher{
+		routes: routes,
+	}
+}
+
+// RouterDispatcher is a dispatcher that picks a child dispatcher for each event, based on the event's attributes.
+type RouterDispatcher struct {
+	routes []Route
+}
+
+// Dispatch sends the event to the dispatcher of the first matching route. If it fails, the returned error is a
+// DispatchError which identifies the route.
+func (d *RouterDispatcher) Dispatch(ctx context.Context, e Event) error {
+	for _, route := range d.routes {
+		if route.Filter != nil && !route.Filter.Apply(e) {
+			continue
+		}
+
+		if err := route.Dispatcher.Dispatch(ctx, e); err != nil {
+			return &DispatchError{Dispatcher: route.Name, Err: err}
+		}
+
+		return nil
+	}
+
+	return nil
+}
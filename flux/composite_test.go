@@ -0,0 +1,216 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingDispatcher records the events it dispatches, failing while err is set.
+type recordingDispatcher struct {
+	mu     sync.Mutex
+	err    error
+	events []flux.Event
+}
+
+func (d *recordingDispatcher) Dispatch(_ context.Context, e flux.Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.err != nil {
+		return d.err
+	}
+
+	d.events = append(d.events, e)
+
+	return nil
+}
+
+func TestFanOutDispatcher(t *testing.T) {
+	errBroken := errors.New("broken")
+
+	t.Run("dispatches to every child", func(t *testing.T) {
+		a, b := &recordingDispatcher{}, &recordingDispatcher{}
+		d := flux.NewFanOutDispatcher(flux.FanOutAllOrNothing, map[string]flux.Dispatcher{"a": a, "b": b})
+
+		events := generateEvents(t, 3)
+		for _, e := range events {
+			require.NoError(t, d.Dispatch(context.Background(), e))
+		}
+
+		require.Equal(t, events, a.events)
+		require.Equal(t, events, b.events)
+	})
+
+	t.Run("all or nothing reports the failing child and retries only it", func(t *testing.T) {
+		a, b := &recordingDispatcher{}, &recordingDispatcher{err: errBroken}
+		d := flux.NewFanOutDispatcher(flux.FanOutAllOrNothing, map[string]flux.Dispatcher{"a": a, "b": b})
+
+		e := generateEvents(t, 1)[0]
+
+		err := d.Dispatch(context.Background(), e)
+		require.ErrorIs(t, err, errBroken)
+
+		var dispatchErr *flux.DispatchError
+		require.ErrorAs(t, err, &dispatchErr)
+		require.Equal(t, "b", dispatchErr.Dispatcher)
+
+		b.err = nil
+		require.NoError(t, d.Dispatch(context.Background(), e))
+
+		require.Equal(t, []flux.Event{e}, a.events)
+		require.Equal(t, []flux.Event{e}, b.events)
+	})
+
+	t.Run("all or nothing tracks retries of interleaved events", func(t *testing.T) {
+		a, b := &recordingDispatcher{}, &recordingDispatcher{err: errBroken}
+		d := flux.NewFanOutDispatcher(flux.FanOutAllOrNothing, map[string]flux.Dispatcher{"a": a, "b": b})
+
+		events := generateEvents(t, 2)
+
+		// Events in different partitions of a relay may be retried in any order.
+		require.ErrorIs(t, d.Dispatch(context.Background(), events[0]), errBroken)
+		require.ErrorIs(t, d.Dispatch(context.Background(), events[1]), errBroken)
+
+		b.err = nil
+		require.NoError(t, d.Dispatch(context.Background(), events[1]))
+		require.NoError(t, d.Dispatch(context.Background(), events[0]))
+
+		require.Equal(t, events, a.events)
+		require.Equal(t, []flux.Event{events[1], events[0]}, b.events)
+	})
+
+	t.Run("all or nothing forgets deliveries outside of the retry window", func(t *testing.T) {
+		a, b := &recordingDispatcher{}, &recordingDispatcher{err: errBroken}
+		d := flux.NewFanOutDispatcher(
+			flux.FanOutAllOrNothing,
+			map[string]flux.Dispatcher{"a": a, "b": b},
+			flux.WithFanOutRetryWindow(10),
+		)
+
+		events := generateEvents(t, 12)
+
+		require.ErrorIs(t, d.Dispatch(context.Background(), events[0]), errBroken)
+		require.ErrorIs(t, d.Dispatch(context.Background(), events[1]), errBroken)
+
+		// The relay has moved on, e.g. because it dead-lettered the first events, so the first event falls outside of
+		// the window while the second is still inside it.
+		b.err = nil
+		require.NoError(t, d.Dispatch(context.Background(), events[11]))
+
+		// An event which is retried after it is forgotten is dispatched to every child again.
+		require.NoError(t, d.Dispatch(context.Background(), events[0]))
+		require.NoError(t, d.Dispatch(context.Background(), events[1]))
+
+		require.Equal(t, []flux.Event{events[0], events[1], events[11], events[0]}, a.events)
+		require.Equal(t, []flux.Event{events[11], events[0], events[1]}, b.events)
+	})
+
+	t.Run("dispatches events concurrently", func(t *testing.T) {
+		// The child only returns once both events are being dispatched at the same time.
+		var arrived sync.WaitGroup
+		arrived.Add(2)
+
+		child := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			arrived.Done()
+			arrived.Wait()
+
+			return nil
+		})
+
+		d := flux.NewFanOutDispatcher(flux.FanOutAllOrNothing, map[string]flux.Dispatcher{"a": child})
+
+		errs := make(chan error, 2)
+		for _, e := range generateEvents(t, 2) {
+			go func(e flux.Event) {
+				errs <- d.Dispatch(context.Background(), e)
+			}(e)
+		}
+
+		for i := 0; i < 2; i++ {
+			select {
+			case err := <-errs:
+				require.NoError(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("events were not dispatched concurrently")
+			}
+		}
+	})
+
+	t.Run("best effort reports failures without returning them", func(t *testing.T) {
+		a, b := &recordingDispatcher{}, &recordingDispatcher{err: errBroken}
+
+		var failed []string
+		d := flux.NewFanOutDispatcher(
+			flux.FanOutBestEffort,
+			map[string]flux.Dispatcher{"a": a, "b": b},
+			flux.WithFanOutErrorFunc(func(name string, _ flux.Event, err error) {
+				require.ErrorIs(t, err, errBroken)
+				failed = append(failed, name)
+			}),
+		)
+
+		events := generateEvents(t, 2)
+		for _, e := range events {
+			require.NoError(t, d.Dispatch(context.Background(), e))
+		}
+
+		require.Equal(t, events, a.events)
+		require.Equal(t, []string{"b", "b"}, failed)
+	})
+}
+
+func TestRouterDispatcher(t *testing.T) {
+	events := generateEvents(t, 10)
+
+	var first, second, fallback recordingDispatcher
+
+	d := flux.NewRouterDispatcher(
+		flux.Route{Name: "first", Filter: flux.MatchTopics(events[0].Topic()), Dispatcher: &first},
+		flux.Route{Name: "second", Filter: flux.MatchKey(events[1].Key()), Dispatcher: &second},
+		flux.Route{Name: "fallback", Dispatcher: &fallback},
+	)
+
+	var wantFirst, wantSecond, wantFallback []flux.Event
+	for _, e := range events {
+		require.NoError(t, d.Dispatch(context.Background(), e))
+
+		switch {
+		case e.Topic() == events[0].Topic():
+			wantFirst = append(wantFirst, e)
+		case e.Key() == events[1].Key():
+			wantSecond = append(wantSecond, e)
+		default:
+			wantFallback = append(wantFallback, e)
+		}
+	}
+
+	require.Equal(t, wantFirst, first.events)
+	require.Equal(t, wantSecond, second.events)
+	require.Equal(t, wantFallback, fallback.events)
+
+	t.Run("reports the failing route", func(t *testing.T) {
+		errBroken := errors.New("broken")
+		d := flux.NewRouterDispatcher(flux.Route{Name: "broken", Dispatcher: &recordingDispatcher{err: errBroken}})
+
+		err := d.Dispatch(context.Background(), events[0])
+		require.ErrorIs(t, err, errBroken)
+
+		var dispatchErr *flux.DispatchError
+		require.ErrorAs(t, err, &dispatchErr)
+		require.Equal(t, "broken", dispatchErr.Dispatcher)
+	})
+
+	t.Run("drops unmatched events", func(t *testing.T) {
+		var target recordingDispatcher
+		d := flux.NewRouterDispatcher(flux.Route{Name: "none", Filter: flux.MatchKey("no-such-key"), Dispatcher: &target})
+
+		require.NoError(t, d.Dispatch(context.Background(), events[0]))
+		require.Empty(t, target.events)
+	})
+}
@@ -0,0 +1,315 @@
+package flux
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	// ErrUnknownCompression is an error that is returned when data is compressed using an unknown algorithm.
+	ErrUnknownCompression = errors.New("unknown compression")
+
+	// ErrDecompressedTooLarge is an error that is returned when data decompresses to more than the configured maximum
+	// size.
+	ErrDecompressedTooLarge = errors.New("decompressed data too large")
+)
+
+// DefaultMaxDecompressedSize is the maximum size, in bytes, that compressed data may decompress to, unless a different
+// maximum is configured. It guards against small payloads which decompress to exhaust memory.
+const DefaultMaxDecompressedSize = 64 << 20
+
+// Compression identifies the algorithm used to compress encoded events. Its value is recorded alongside compressed
+// data, so existing values must never change.
+type Compression byte
+
+const (
+	// CompressionNone leaves data uncompressed.
+	CompressionNone Compression = 0
+
+	// CompressionGzip compresses data using gzip.
+	CompressionGzip Compression = 1
+
+	// CompressionZstd compresses data using zstd.
+	CompressionZstd Compression = 2
+
+	// CompressionSnappy compresses data using the snappy block format.
+	CompressionSnappy Compression = 3
+)
+
+// The zstd encoder is expensive to create, but safe for concurrent use, so it is shared.
+var (
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdErr     error
+)
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEncoder, zstdErr = zstd.NewWriter(nil)
+	})
+
+	return zstdErr
+}
+
+// String returns the name of the compression algorithm.
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	case CompressionSnappy:
+		return "snappy"
+	default:
+		return fmt.Sprintf("Compression(%d)", byte(c))
+	}
+}
+
+// compress compresses the data using the algorithm.
+func (c Compression) compress(data []byte) ([]byte, error) {
+	switch c {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		var buf bytes.Buffer
+
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	case CompressionZstd:
+		if err := initZstd(); err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case CompressionSnappy:
+		return s2.EncodeSnappy(nil, data), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCompression, c)
+	}
+}
+
+// newDecompressor returns a decompressor which decompresses data to at most limit bytes.
+func newDecompressor(limit int) *decompressor {
+	return &decompressor{limit: limit}
+}
+
+// decompressor decompresses data, compressed using any algorithm, to at most a fixed number of bytes. A zstd decoder's
+// memory limit is fixed when it is created, so each decompressor creates its own decoder, the first time it is needed.
+type decompressor struct {
+	limit int
+
+	zstdOnce    sync.Once
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+}
+
+// initZstd creates the decompressor's zstd decoder. Frames always have a window of at least zstd.MinWindowSize, so the
+// decoder allows at least that much memory, and smaller limits are checked after decoding.
+func (d *decompressor) initZstd() error {
+	d.zstdOnce.Do(func() {
+		maxMemory := uint64(max(d.limit, zstd.MinWindowSize))
+		d.zstdDecoder, d.zstdErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxMemory))
+	})
+
+	return d.zstdErr
+}
+
+// decompress decompresses data which was compressed using the algorithm. It returns ErrDecompressedTooLarge if the
+// data decompresses to more than the decompressor's limit.
+func (d *decompressor) decompress(c Compression, data []byte) ([]byte, error) {
+	limit := d.limit
+
+	switch c {
+	case CompressionNone:
+		return data, nil
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		defer r.Close()
+
+		// Reading a byte past the limit tells data which reaches it apart from data which exceeds it. No data can
+		// exceed the largest limit, which must not overflow.
+		n := int64(limit)
+		if n < math.MaxInt64 {
+			n++
+		}
+
+		data, err := io.ReadAll(io.LimitReader(r, n))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+
+		if len(data) > limit {
+			return nil, fmt.Errorf("gzip: %w: more than %d bytes", ErrDecompressedTooLarge, limit)
+		}
+
+		return data, nil
+	case CompressionZstd:
+		if err := d.initZstd(); err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+
+		data, err := d.zstdDecoder.DecodeAll(data, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, fmt.Errorf("zstd: %w: more than %d bytes", ErrDecompressedTooLarge, limit)
+		} else if err != nil {
+			return nil, fmt.Errorf("zstd: %w", err)
+		}
+
+		if len(data) > limit {
+			return nil, fmt.Errorf("zstd: %w: more than %d bytes", ErrDecompressedTooLarge, limit)
+		}
+
+		return data, nil
+	case CompressionSnappy:
+		// The decoded length is recorded at the start of the data, so it is checked before anything is allocated.
+		n, err := s2.DecodedLen(data)
+		if err != nil {
+			return nil, fmt.Errorf("snappy: %w", err)
+		}
+
+		if n > limit {
+			return nil, fmt.Errorf("snappy: %w: %d bytes is more than %d", ErrDecompressedTooLarge, n, limit)
+		}
+
+		data, err = s2.Decode(nil, data)
+		if err != nil {
+			return nil, fmt.Errorf("snappy: %w", err)
+		}
+
+		return data, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCompression, c)
+	}
+}
+
+// DefaultCompressionThreshold is the size, in bytes, from which a CompressionCodec compresses encoded events, unless
+// it is configured with a different threshold.
+const DefaultCompressionThreshold = 1024
+
+// Compile-time assertion that CompressionCodec implements the Codec interface.
+var _ Codec = (*CompressionCodec)(nil)
+
+// NewCompressionCodec returns a codec which compresses the data of another codec using the given algorithm, once it
+// reaches the codec's threshold.
+//
+// The encoded data is prefixed with a byte which records the algorithm it was compressed with, so data compressed
+// using any algorithm can be decoded, regardless of the algorithm the codec compresses with.
+func NewCompressionCodec(codec Codec, compression Compression, opts ...CompressionCodecOption) *CompressionCodec {
+	config := CompressionCodecConfig{
+		Threshold:           DefaultCompressionThreshold,
+		MaxDecompressedSize: DefaultMaxDecompressedSize,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &CompressionCodec{
+		codec:        codec,
+		compression:  compression,
+		config:       &config,
+		decompressor: newDecompressor(config.MaxDecompressedSize),
+	}
+}
+
+// CompressionCodec is a Codec which compresses the data of another codec.
+type CompressionCodec struct {
+	codec        Codec
+	compression  Compression
+	config       *CompressionCodecConfig
+	decompressor *decompressor
+}
+
+// CompressionCodecConfig holds the configuration of a CompressionCodec.
+type CompressionCodecConfig struct {
+	// The size, in bytes, from which encoded events are compressed. Smaller events are not worth compressing.
+	Threshold int
+
+	// The maximum size, in bytes, that compressed data may decompress to.
+	MaxDecompressedSize int
+}
+
+// CompressionCodecOption is an interface that allows for functional options to be applied to a CompressionCodecConfig.
+type CompressionCodecOption interface {
+	Apply(*CompressionCodecConfig)
+}
+
+// CompressionCodecOptionFunc is a function type that implements the CompressionCodecOption interface.
+type CompressionCodecOptionFunc func(*CompressionCodecConfig)
+
+// Apply applies the function to the codec config.
+func (f CompressionCodecOptionFunc) Apply(config *CompressionCodecConfig) {
+	f(config)
+}
+
+// WithCompressionThreshold sets the size, in bytes, from which encoded events are compressed.
+func WithCompressionThreshold(threshold int) CompressionCodecOption {
+	return CompressionCodecOptionFunc(func(config *CompressionCodecConfig) {
+		config.Threshold = threshold
+	})
+}
+
+// WithMaxDecompressedSize sets the maximum size, in bytes, that compressed data may decompress to. Data which exceeds
+// it fails to decode with ErrDecompressedTooLarge.
+func WithMaxDecompressedSize(size int) CompressionCodecOption {
+	return CompressionCodecOptionFunc(func(config *CompressionCodecConfig) {
+		config.MaxDecompressedSize = size
+	})
+}
+
+// Encode encodes the event using the inner codec, and compresses the data if it is large enough. The data is left
+// uncompressed if compressing it does not make it smaller.
+func (codec *CompressionCodec) Encode(e Event) ([]byte, error) {
+	data, err := codec.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if codec.compression != CompressionNone && len(data) >= codec.config.Threshold {
+		compressed, err := codec.compression.compress(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress event: %w", err)
+		}
+
+		if len(compressed) < len(data) {
+			return append([]byte{byte(codec.compression)}, compressed...), nil
+		}
+	}
+
+	return append([]byte{byte(CompressionNone)}, data...), nil
+}
+
+// Decode decompresses the data using the algorithm recorded in its first byte, and decodes it using the inner codec.
+func (codec *CompressionCodec) Decode(data []byte) (Event, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: missing header", ErrUnknownCompression)
+	}
+
+	data, err := codec.decompressor.decompress(Compression(data[0]), data[1:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress event: %w", err)
+	}
+
+	return codec.codec.Decode(data)
+}
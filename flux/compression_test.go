@@ -0,0 +1,135 @@
+package flux_test
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionCodec(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	algorithms := []flux.Compression{
+		flux.CompressionNone,
+		flux.CompressionGzip,
+		flux.CompressionZstd,
+		flux.CompressionSnappy,
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		for _, algorithm := range algorithms {
+			t.Run(algorithm.String(), func(t *testing.T) {
+				codec := flux.NewCompressionCodec(flux.NewJSONCodec(), algorithm)
+
+				e := generator.generateEvent("test-topic", "test-key")
+				e.payload = bytes.Repeat([]byte("test"), 1000)
+
+				data, err := codec.Encode(e)
+				require.NoError(t, err)
+				require.Equal(t, byte(algorithm), data[0])
+
+				got, err := codec.Decode(data)
+				require.NoError(t, err)
+				requireDecodedEventEqual(t, e, got)
+			})
+		}
+	})
+
+	t.Run("any algorithm is decoded", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = bytes.Repeat([]byte("test"), 1000)
+
+		decoder := flux.NewCompressionCodec(flux.NewJSONCodec(), flux.CompressionGzip)
+
+		for _, algorithm := range algorithms {
+			data, err := flux.NewCompressionCodec(flux.NewJSONCodec(), algorithm).Encode(e)
+			require.NoError(t, err)
+
+			got, err := decoder.Decode(data)
+			require.NoError(t, err)
+			requireDecodedEventEqual(t, e, got)
+		}
+	})
+
+	t.Run("threshold", func(t *testing.T) {
+		codec := flux.NewCompressionCodec(
+			flux.NewJSONCodec(),
+			flux.CompressionZstd,
+			flux.WithCompressionThreshold(4096),
+		)
+
+		small := generator.generateEvent("test-topic", "test-key")
+		small.payload = bytes.Repeat([]byte("test"), 100)
+
+		data, err := codec.Encode(small)
+		require.NoError(t, err)
+		require.Equal(t, byte(flux.CompressionNone), data[0])
+
+		large := generator.generateEvent("test-topic", "test-key")
+		large.payload = bytes.Repeat([]byte("test"), 2000)
+
+		data, err = codec.Encode(large)
+		require.NoError(t, err)
+		require.Equal(t, byte(flux.CompressionZstd), data[0])
+	})
+
+	t.Run("max decompressed size", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = bytes.Repeat([]byte("test"), 1000)
+
+		decoder := flux.NewCompressionCodec(
+			flux.NewJSONCodec(),
+			flux.CompressionGzip,
+			flux.WithMaxDecompressedSize(2048),
+		)
+
+		for _, algorithm := range algorithms[1:] {
+			t.Run(algorithm.String(), func(t *testing.T) {
+				data, err := flux.NewCompressionCodec(flux.NewJSONCodec(), algorithm).Encode(e)
+				require.NoError(t, err)
+				require.Equal(t, byte(algorithm), data[0])
+
+				_, err = decoder.Decode(data)
+				require.ErrorIs(t, err, flux.ErrDecompressedTooLarge)
+			})
+		}
+	})
+
+	t.Run("largest max decompressed size", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = bytes.Repeat([]byte("test"), 1000)
+
+		decoder := flux.NewCompressionCodec(
+			flux.NewJSONCodec(),
+			flux.CompressionGzip,
+			flux.WithMaxDecompressedSize(math.MaxInt),
+		)
+
+		for _, algorithm := range algorithms {
+			t.Run(algorithm.String(), func(t *testing.T) {
+				data, err := flux.NewCompressionCodec(flux.NewJSONCodec(), algorithm).Encode(e)
+				require.NoError(t, err)
+
+				got, err := decoder.Decode(data)
+				require.NoError(t, err)
+				requireDecodedEventEqual(t, e, got)
+			})
+		}
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		codec := flux.NewCompressionCodec(flux.NewJSONCodec(), flux.CompressionGzip)
+
+		_, err := codec.Decode(nil)
+		require.ErrorIs(t, err, flux.ErrUnknownCompression)
+
+		_, err = codec.Decode([]byte{42, '{', '}'})
+		require.ErrorIs(t, err, flux.ErrUnknownCompression)
+
+		_, err = codec.Decode([]byte{byte(flux.CompressionZstd), 1, 2, 3})
+		require.Error(t, err)
+	})
+}
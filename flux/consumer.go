@@ -0,0 +1,484 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cenkalti/backoff"
+)
+
+// ErrConsumerRunning is an error that is returned when a consumer is started while it is already running.
+var ErrConsumerRunning = errors.New("consumer is already running")
+
+// ConsumerFunc is a function that handles a single event. Returning an error stops the consumer from progressing past
+// the event, which will be handled again once the consumer retries.
+type ConsumerFunc func(ctx context.Context, e Event) error
+
+// Consumer reads events from an event store and passes them to a handler, keeping track of its progress using a named
+// cursor. A consumer resumes from the cursor when it is restarted, so each event is handled at least once.
+type Consumer struct {
+	name    string
+	events  EventReader
+	cursors CursorStore
+	handler ConsumerFunc
+
+	config   *ConsumerConfig
+	observer RelayObserver
+
+	// The lifecycle of the current run, guarded by mu, as the consumer may be shut down from another goroutine. The
+	// shutdown channel is closed to ask the run to stop, and the done channel is closed once it has stopped. Both are
+	// replaced every time the consumer is started.
+	mu       sync.Mutex
+	running  bool
+	shutdown chan struct{}
+	done     chan struct{}
+}
+
+type ConsumerConfig struct {
+	// The backoff strategy to use when retrying operations.
+	BackOff backoff.BackOff
+
+	// The maximum number of events to fetch from the event store at a time.
+	BatchSize uint
+
+	// If set, the cursor is updated once this many events have been handled since the previous update.
+	CheckpointEvents uint
+
+	// If set, the cursor is updated once this much time has passed since the previous update.
+	CheckpointInterval time.Duration
+
+	// If set, the consumer will only handle events which occurred before NOW - StreamLag.
+	StreamLag time.Duration
+
+	// How long the consumer waits for new events once it has handled every event in the event store, before checking
+	// again. If the event store implements EventWaiter, the consumer checks as soon as it is told of new events.
+	PollInterval time.Duration
+
+	// Determines how the consumer handles gaps in the sequences of the event stream. See GapPolicy.
+	GapPolicy GapPolicy
+
+	// When using GapPolicySkip, the amount of time to wait for a gap to be filled before skipping it.
+	GapTimeout time.Duration
+
+	// If set, called with the range of sequences that were skipped when using GapPolicySkip.
+	OnGapSkipped func(from, to uint)
+
+	// If set, events which the handler fails to handle MaxHandleAttempts times are handed to the sink, after which the
+	// consumer moves on to the next event. Otherwise, the consumer retries a failing event indefinitely.
+	DeadLetterSink DeadLetterSink
+
+	// The number of times the consumer attempts to handle an event before handing it to the DeadLetterSink.
+	MaxHandleAttempts int
+
+	// Notified of the consumer's activity, in the same way as a Relay's. If nil, the consumer logs its activity using
+	// slog.Default().
+	Observer RelayObserver
+}
+
+var DefaultConsumerConfig = ConsumerConfig{
+	BackOff:      backoff.NewConstantBackOff(5 * time.Second),
+	BatchSize:    5,
+	PollInterval: time.Second,
+}
+
+// NewConsumer returns an instance of a Consumer.
+//
+// By default, the consumer's cursor is updated after every batch of events. Use WithCheckpointEvents and
+// WithCheckpointInterval to update it less often, at the cost of handling more events again after a restart.
+func NewConsumer(
+	name string,
+	events EventReader,
+	cursors CursorStore,
+	handler ConsumerFunc,
+	opts ...ConsumerOption,
+) *Consumer {
+	defaultConfig := DefaultConsumerConfig // make a copy so we don't modify the default config.
+
+	c := Consumer{
+		name:    name,
+		events:  events,
+		cursors: cursors,
+		handler: handler,
+		config:  &defaultConfig,
+		running: false,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(c.config)
+	}
+
+	c.observer = c.config.Observer
+	if c.observer == nil {
+		c.observer = NewSlogObserver(nil)
+	}
+
+	return &c
+}
+
+// ConsumerOption is an interface that allows for functional options to be applied to a ConsumerConfig.
+type ConsumerOption interface {
+	Apply(*ConsumerConfig)
+}
+
+// ConsumerOptionFunc is a function type that implements the ConsumerOption interface.
+type ConsumerOptionFunc func(*ConsumerConfig)
+
+// Apply applies the function to the consumer.
+func (f ConsumerOptionFunc) Apply(config *ConsumerConfig) {
+	f(config)
+}
+
+// WithConsumerBackOff sets the backoff strategy of the consumer.
+func WithConsumerBackOff(backOff backoff.BackOff) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		config.BackOff = backOff
+	})
+}
+
+// WithBatchSize sets the number of events the consumer fetches at a time.
+func WithBatchSize(batchSize uint) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		if batchSize > 0 {
+			config.BatchSize = batchSize
+		}
+	})
+}
+
+// WithCheckpointEvents sets the number of events after which the consumer updates its cursor.
+func WithCheckpointEvents(count uint) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		config.CheckpointEvents = count
+	})
+}
+
+// WithCheckpointInterval sets the interval after which the consumer updates its cursor.
+func WithCheckpointInterval(interval time.Duration) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		config.CheckpointInterval = interval
+	})
+}
+
+// WithConsumerPollInterval sets how long the consumer waits for new events before checking again, when the event store
+// has no more events.
+func WithConsumerPollInterval(interval time.Duration) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		if interval > 0 {
+			config.PollInterval = interval
+		}
+	})
+}
+
+// WithConsumerGapPolicy sets the gap policy of the consumer, and the amount of time to wait for a gap to be filled
+// before it is skipped when using GapPolicySkip.
+func WithConsumerGapPolicy(policy GapPolicy, timeout time.Duration) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		config.GapPolicy = policy
+		config.GapTimeout = timeout
+	})
+}
+
+// WithConsumerGapSkippedFunc sets a function which is called with the range of sequences that were skipped by the
+// consumer.
+func WithConsumerGapSkippedFunc(fn func(from, to uint)) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		config.OnGapSkipped = fn
+	})
+}
+
+// WithConsumerDeadLetterSink sets the sink which receives events that the handler has failed to handle maxAttempts
+// times, allowing the consumer to advance past them.
+func WithConsumerDeadLetterSink(sink DeadLetterSink, maxAttempts int) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		config.DeadLetterSink = sink
+		config.MaxHandleAttempts = maxAttempts
+	})
+}
+
+// WithConsumerObserver sets the observers which are notified of the consumer's activity, in place of logging it using
+// slog.Default().
+func WithConsumerObserver(observers ...RelayObserver) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		if len(observers) == 1 {
+			config.Observer = observers[0]
+			return
+		}
+
+		config.Observer = multiObserver(observers)
+	})
+}
+
+// WithConsumerStreamLag sets the stream lag of the consumer.
+func WithConsumerStreamLag(lag time.Duration) ConsumerOption {
+	return ConsumerOptionFunc(func(config *ConsumerConfig) {
+		config.StreamLag = lag
+	})
+}
+
+// Start consumes events until the consumer is shut down, the context is cancelled, or the backoff strategy gives up.
+// It returns nil if the consumer is shut down, the context's error if it is cancelled, and ErrConsumerRunning if the
+// consumer is already running. A consumer which has stopped may be started again, in which case it resumes from its
+// cursor.
+func (c *Consumer) Start(ctx context.Context) error {
+	c.mu.Lock()
+	if c.running {
+		c.mu.Unlock()
+		return ErrConsumerRunning
+	}
+
+	c.running = true
+	c.shutdown = make(chan struct{})
+	c.done = make(chan struct{})
+	shutdown, done := c.shutdown, c.done
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.running = false
+		c.mu.Unlock()
+
+		close(done)
+	}()
+
+	// The stream is created on the first attempt that manages to load the consumer's cursor, and is shared between
+	// retries so that the consumer resumes from its last position.
+	var (
+		s  *stream
+		cp *checkpoint
+	)
+
+	fn := func() error {
+		if s == nil {
+			cursor, err := lookupOrCreateCursor(ctx, c.cursors, c.name, 0)
+			if err != nil {
+				return err
+			}
+
+			s = &stream{
+				buffer:      make([]Event, 0),
+				bufferSize:  c.config.BatchSize,
+				lag:         c.config.StreamLag,
+				position:    cursor.Sequence(),
+				gapPolicy:   c.config.GapPolicy,
+				gapTimeout:  c.config.GapTimeout,
+				onGap:       c.config.OnGapSkipped,
+				deadLetter:  c.config.DeadLetterSink,
+				maxAttempts: c.config.MaxHandleAttempts,
+				observer:    c.observer,
+			}
+
+			cp = newCheckpoint(cursor, c.config.CheckpointEvents, c.config.CheckpointInterval)
+		}
+
+		return c.consume(ctx, s, cp, shutdown)
+	}
+
+	notify := func(err error, next time.Duration) {
+		c.observer.RetryScheduled(ctx, err, next)
+	}
+
+	// Stop retrying as soon as the consumer is shut down, rather than once the next attempt is due.
+	retryCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-shutdown:
+			cancel()
+		case <-retryCtx.Done():
+		}
+	}()
+
+	err := backoff.RetryNotify(fn, backoff.WithContext(c.config.BackOff, retryCtx), notify)
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// Being shut down is how a consumer is meant to stop, even if it was waiting to retry a failure at the time.
+	if retryCtx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+func (c *Consumer) consume(ctx context.Context, s *stream, cp *checkpoint, shutdown <-chan struct{}) error {
+	// Ensure the cursor reflects every handled event before returning, even if the context has been cancelled.
+	defer func() {
+		_ = cp.commit(context.WithoutCancel(ctx), c.cursors, s.position)
+	}()
+
+	// Discard any events left over from a failed attempt, they are fetched again from the current position.
+	s.buffer = s.buffer[:0]
+
+	handler := DispatcherFunc(c.handler)
+
+	// Start main loop.
+	for {
+		select {
+		case <-shutdown:
+			c.observer.Shutdown(ctx)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			if err := s.spool(ctx, c.events); err != nil {
+				if !errors.Is(err, ErrEventNotFound) && !errors.Is(err, ErrNoMoreEvents) {
+					return fmt.Errorf("failed to spool events: %w", err)
+				}
+
+				// The consumer has caught up with the event store, so record its position while it waits for new
+				// events.
+				if err := cp.commit(ctx, c.cursors, s.position); err != nil {
+					return err
+				}
+
+				waitForEvents(ctx, c.events, s.position, s.lag, c.config.PollInterval, shutdown, c.observer)
+				continue
+			}
+
+			// Waiting for a gap to be filled is not a failure, so the consumer waits without backing off.
+			var wait time.Duration
+
+			for len(s.buffer) > 0 {
+				e := s.buffer[0]
+				s.buffer = s.buffer[1:]
+
+				var err error
+				if wait, err = gapWait(s.maybeDispatchEvent(ctx, e, handler)); err != nil {
+					return err
+				}
+
+				if wait > 0 {
+					s.buffer = s.buffer[:0]
+					break
+				}
+
+				if cp.due(s.position) {
+					if err := cp.commit(ctx, c.cursors, s.position); err != nil {
+						return err
+					}
+				}
+			}
+
+			if cp.everyBatch() {
+				if err := cp.commit(ctx, c.cursors, s.position); err != nil {
+					return err
+				}
+			}
+
+			if wait > 0 {
+				sleep(ctx, min(wait, c.config.PollInterval), shutdown)
+			}
+
+			// Reset the backoff strategy if we successfully processed an event batch.
+			c.config.BackOff.Reset()
+		}
+	}
+}
+
+// Shutdown gracefully shuts down the consumer, and blocks until it has finished handling its current event and updated
+// its cursor, or the context expires, in which case the context's error is returned. The consumer keeps shutting down
+// in the background if the context expires. Shutting down a consumer which is not running does nothing.
+func (c *Consumer) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	if !c.running {
+		c.mu.Unlock()
+		return nil
+	}
+
+	select {
+	case <-c.shutdown:
+		// The consumer is already shutting down.
+	default:
+		close(c.shutdown)
+	}
+
+	done := c.done
+	c.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// lookupOrCreateCursor returns the cursor with the given name, creating it at the given sequence if it does not exist.
+func lookupOrCreateCursor(ctx context.Context, cursors CursorStore, name string, sequence uint) (Cursor, error) {
+	cursor, err := cursors.LookupCursorByName(ctx, name)
+	if err == nil {
+		return cursor, nil
+	}
+
+	if !errors.Is(err, ErrCursorNotFound) {
+		return nil, fmt.Errorf("failed to lookup cursor: %w", err)
+	}
+
+	cursor, err = cursors.CreateCursor(ctx, name, sequence)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cursor: %w", err)
+	}
+
+	return cursor, nil
+}
+
+// newCheckpoint returns a checkpoint for the cursor, which is due after the given number of events or amount of time.
+// If neither is set, the cursor is updated after every batch.
+func newCheckpoint(cursor Cursor, events uint, interval time.Duration) *checkpoint {
+	return &checkpoint{
+		cursorID:  cursor.ID(),
+		position:  cursor.Sequence(),
+		updatedAt: time.Now(),
+		events:    events,
+		interval:  interval,
+	}
+}
+
+// checkpoint tracks the position last written to a cursor.
+type checkpoint struct {
+	cursorID  string
+	position  uint
+	updatedAt time.Time
+
+	events   uint
+	interval time.Duration
+}
+
+// due returns true if the configured number of events, or amount of time, has passed since the last update.
+func (cp *checkpoint) due(position uint) bool {
+	if cp.events > 0 && position-cp.position >= cp.events {
+		return true
+	}
+
+	if cp.interval > 0 && time.Since(cp.updatedAt) >= cp.interval {
+		return true
+	}
+
+	return false
+}
+
+// everyBatch returns true if no frequency is configured, in which case the cursor is updated after every batch.
+func (cp *checkpoint) everyBatch() bool {
+	return cp.events == 0 && cp.interval == 0
+}
+
+// commit updates the cursor to the given position, if it has moved since the last update.
+func (cp *checkpoint) commit(ctx context.Context, cursors CursorWriter, position uint) error {
+	if position == cp.position {
+		return nil
+	}
+
+	if err := cursors.UpdateCursor(ctx, cp.cursorID, position); err != nil {
+		return fmt.Errorf("failed to update cursor: %w", err)
+	}
+
+	cp.position = position
+	cp.updatedAt = time.Now()
+
+	return nil
+}
@@ -0,0 +1,440 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/google/uuid"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/nickcorin/toolkit/flux/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func generateEvents(t *testing.T, count int) []flux.Event {
+	t.Helper()
+
+	g := NewEventGenerator(t)
+
+	events := make([]flux.Event, count)
+	for i := range events {
+		e := g.generateRandomEvent()
+		e.timestamp = e.timestamp.Add(-time.Minute)
+		events[i] = e
+	}
+
+	return events
+}
+
+func TestConsumer(t *testing.T) {
+	const consumerName = "test-consumer"
+
+	events := generateEvents(t, 10)
+
+	t.Run("creates a cursor on first run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: uuid.NewString(), name: consumerName}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), consumerName).Return(nil, flux.ErrCursorNotFound)
+		cursors.EXPECT().CreateCursor(gomock.Any(), consumerName, uint(0)).Return(cursor, nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(5)).Return(nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(10)).Return(nil)
+
+		var handled []flux.Event
+		handler := func(ctx context.Context, e flux.Event) error {
+			handled = append(handled, e)
+			return nil
+		}
+
+		reader := &staticEventReader{events: events}
+		consumer := flux.NewConsumer(
+			consumerName,
+			reader,
+			cursors,
+			handler,
+			flux.WithConsumerBackOff(&backoff.StopBackOff{}),
+		)
+
+		err := runConsumer(t, consumer, reader)
+		require.NoError(t, err)
+		require.Equal(t, events, handled)
+	})
+
+	t.Run("resumes from an existing cursor", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: uuid.NewString(), name: consumerName, sequence: 7}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), consumerName).Return(cursor, nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(10)).Return(nil)
+
+		var handled []flux.Event
+		handler := func(ctx context.Context, e flux.Event) error {
+			handled = append(handled, e)
+			return nil
+		}
+
+		reader := &staticEventReader{events: events}
+		consumer := flux.NewConsumer(
+			consumerName,
+			reader,
+			cursors,
+			handler,
+			flux.WithConsumerBackOff(&backoff.StopBackOff{}),
+		)
+
+		err := runConsumer(t, consumer, reader)
+		require.NoError(t, err)
+		require.Equal(t, events[7:], handled)
+	})
+
+	t.Run("checkpoints every n events", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: uuid.NewString(), name: consumerName}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), consumerName).Return(cursor, nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(3)).Return(nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(6)).Return(nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(9)).Return(nil)
+
+		// The remaining event is committed once the consumer has caught up with the event store.
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(10)).Return(nil)
+
+		handler := func(ctx context.Context, e flux.Event) error {
+			return nil
+		}
+
+		reader := &staticEventReader{events: events}
+		consumer := flux.NewConsumer(
+			consumerName,
+			reader,
+			cursors,
+			handler,
+			flux.WithConsumerBackOff(&backoff.StopBackOff{}),
+			flux.WithBatchSize(4),
+			flux.WithCheckpointEvents(3),
+		)
+
+		err := runConsumer(t, consumer, reader)
+		require.NoError(t, err)
+	})
+
+	t.Run("checkpoints handled events when the handler fails", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: uuid.NewString(), name: consumerName}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), consumerName).Return(cursor, nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(2)).Return(nil)
+
+		errHandler := errors.New("handler failed")
+		handler := func(ctx context.Context, e flux.Event) error {
+			if e.Sequence() == 3 {
+				return errHandler
+			}
+
+			return nil
+		}
+
+		reader := &staticEventReader{events: events}
+		consumer := flux.NewConsumer(
+			consumerName,
+			reader,
+			cursors,
+			handler,
+			flux.WithConsumerBackOff(&backoff.StopBackOff{}),
+		)
+
+		err := runConsumer(t, consumer, reader)
+		require.ErrorIs(t, err, errHandler)
+	})
+
+	t.Run("reports its activity to the observer", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: uuid.NewString(), name: consumerName}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), consumerName).Return(cursor, nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), gomock.Any()).Return(nil).AnyTimes()
+
+		// The third event fails on its first attempt.
+		var failed bool
+		handler := func(ctx context.Context, e flux.Event) error {
+			if e.Sequence() == 3 && !failed {
+				failed = true
+				return errors.New("temporary failure")
+			}
+
+			return nil
+		}
+
+		observer := &recordingObserver{}
+		reader := &staticEventReader{events: events}
+		consumer := flux.NewConsumer(
+			consumerName,
+			reader,
+			cursors,
+			handler,
+			flux.WithConsumerBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+			flux.WithConsumerObserver(observer),
+		)
+
+		err := runConsumer(t, consumer, reader)
+		require.NoError(t, err)
+
+		require.Equal(t, events, observer.dispatched)
+		require.Equal(t, []flux.Event{events[2]}, observer.failed)
+		require.Equal(t, 1, observer.retries)
+		require.Equal(t, 1, observer.shutdowns)
+	})
+
+	t.Run("dead-letters events the handler keeps failing to handle", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: uuid.NewString(), name: consumerName}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), consumerName).Return(cursor, nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), gomock.Any()).Return(nil).AnyTimes()
+
+		var handled []flux.Event
+		handler := func(ctx context.Context, e flux.Event) error {
+			if e.Sequence() == 3 {
+				return errors.New("handler failed")
+			}
+
+			handled = append(handled, e)
+
+			return nil
+		}
+
+		var letters []*flux.DeadLetter
+		sink := flux.DeadLetterSinkFunc(func(ctx context.Context, letter *flux.DeadLetter) error {
+			letters = append(letters, letter)
+			return nil
+		})
+
+		reader := &staticEventReader{events: events}
+		consumer := flux.NewConsumer(
+			consumerName,
+			reader,
+			cursors,
+			handler,
+			flux.WithConsumerBackOff(backoff.NewConstantBackOff(time.Millisecond)),
+			flux.WithConsumerDeadLetterSink(sink, 3),
+		)
+
+		err := runConsumer(t, consumer, reader)
+		require.NoError(t, err)
+
+		require.Equal(t, append(append([]flux.Event{}, events[:2]...), events[3:]...), handled)
+		require.Len(t, letters, 1)
+		require.Equal(t, events[2], letters[0].Event)
+		require.Equal(t, 3, letters[0].Attempts)
+		require.Equal(t, "handler failed", letters[0].Cause)
+	})
+}
+
+func TestConsumer_GapPolicy(t *testing.T) {
+	events := generateEvents(t, 5)
+
+	// Remove the third event to create a gap in the sequences.
+	withGap := append(append([]flux.Event{}, events[:2]...), events[3:]...)
+
+	setup := func(t *testing.T, opts ...flux.ConsumerOption) (*flux.Consumer, *staticEventReader, *[]flux.Event) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: uuid.NewString(), name: "test-consumer"}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), cursor.Name()).Return(cursor, nil)
+		cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), gomock.Any()).Return(nil).AnyTimes()
+
+		var handled []flux.Event
+		handler := func(ctx context.Context, e flux.Event) error {
+			handled = append(handled, e)
+			return nil
+		}
+
+		reader := &staticEventReader{events: withGap}
+
+		return flux.NewConsumer(cursor.Name(), reader, cursors, handler, opts...), reader, &handled
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		consumer, reader, handled := setup(t, flux.WithConsumerBackOff(&backoff.StopBackOff{}))
+
+		err := runConsumer(t, consumer, reader)
+		require.ErrorIs(t, err, flux.ErrGapDetected)
+		require.Equal(t, events[:2], *handled)
+	})
+
+	t.Run("skip after timeout", func(t *testing.T) {
+		const timeout = 50 * time.Millisecond
+
+		var gaps [][2]uint
+
+		// Waiting for the gap is not a failure, so the consumer does not need to retry.
+		consumer, reader, handled := setup(t,
+			flux.WithConsumerBackOff(&backoff.StopBackOff{}),
+			flux.WithConsumerGapPolicy(flux.GapPolicySkip, timeout),
+			flux.WithConsumerGapSkippedFunc(func(from, to uint) { gaps = append(gaps, [2]uint{from, to}) }),
+			flux.WithConsumerPollInterval(5*time.Millisecond),
+		)
+
+		start := time.Now()
+		err := runConsumer(t, consumer, reader)
+		require.NoError(t, err)
+
+		require.Equal(t, withGap, *handled)
+		require.Equal(t, [][2]uint{{3, 3}}, gaps)
+		require.GreaterOrEqual(t, time.Since(start), timeout)
+	})
+}
+
+func TestConsumer_Idle_StreamLag(t *testing.T) {
+	store := &countingEventStore{MemoryEventStore: flux.NewMemoryEventStore()}
+
+	// The event stays within the stream lag for the duration of the test, so it is never read.
+	_, err := store.CreateEvent(context.Background(), "topic", "key")
+	require.NoError(t, err)
+
+	consumer := flux.NewConsumer(
+		"test-consumer",
+		store,
+		flux.NewMemoryCursorStore(),
+		func(ctx context.Context, e flux.Event) error {
+			t.Error("event within the stream lag was handled")
+			return nil
+		},
+		flux.WithConsumerBackOff(&backoff.StopBackOff{}),
+		flux.WithConsumerPollInterval(time.Hour),
+		flux.WithConsumerStreamLag(time.Minute),
+	)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- consumer.Start(context.Background())
+	}()
+
+	// The consumer waits for the event to leave the stream lag, rather than fetching it again and again.
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int64(1), store.calls.Load())
+
+	require.NoError(t, consumer.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+}
+
+func TestConsumer_Shutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+
+	cursor := &testCursor{id: uuid.NewString(), name: "test-consumer"}
+
+	cursors := mocks.NewMockCursorStore(ctrl)
+	cursors.EXPECT().LookupCursorByName(gomock.Any(), gomock.Any()).Return(cursor, nil).AnyTimes()
+	cursors.EXPECT().UpdateCursor(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	consumer := flux.NewConsumer(
+		cursor.Name(),
+		&staticEventReader{},
+		cursors,
+		func(ctx context.Context, e flux.Event) error { return nil },
+		flux.WithConsumerBackOff(&backoff.StopBackOff{}),
+		flux.WithConsumerPollInterval(time.Millisecond),
+	)
+
+	start := func(ctx context.Context) <-chan error {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- consumer.Start(ctx)
+		}()
+
+		// Wait for the consumer to start, so that it may be shut down.
+		time.Sleep(10 * time.Millisecond)
+
+		return errs
+	}
+
+	wait := func(errs <-chan error) error {
+		select {
+		case err := <-errs:
+			return err
+		case <-time.After(time.Second):
+			t.Fatal("consumer did not stop")
+			return nil
+		}
+	}
+
+	// An idle consumer keeps waiting for new events until it is shut down.
+	errs := start(context.Background())
+	require.ErrorIs(t, consumer.Start(context.Background()), flux.ErrConsumerRunning)
+	require.NoError(t, consumer.Shutdown(context.Background()))
+
+	// The consumer has already stopped by the time Shutdown returns.
+	select {
+	case err := <-errs:
+		require.NoError(t, err)
+	default:
+		t.Fatal("consumer did not stop before Shutdown returned")
+	}
+
+	// A consumer which has been shut down can be started again.
+	errs = start(context.Background())
+	require.NoError(t, consumer.Shutdown(context.Background()))
+	require.NoError(t, wait(errs))
+
+	// Shutting down a consumer which is not running does nothing.
+	require.NoError(t, consumer.Shutdown(context.Background()))
+
+	// Cancelling the context stops the consumer too, and Start returns the context's error.
+	ctx, cancel := context.WithCancel(context.Background())
+	errs = start(ctx)
+	cancel()
+	require.ErrorIs(t, wait(errs), context.Canceled)
+}
+
+func TestConsumer_Shutdown_Timeout(t *testing.T) {
+	handling := make(chan struct{})
+	release := make(chan struct{})
+
+	reader := &staticEventReader{events: []flux.Event{NewEventGenerator(t).generateRandomEvent()}}
+
+	consumer := flux.NewConsumer(
+		"test-consumer",
+		reader,
+		flux.NewMemoryCursorStore(),
+		func(ctx context.Context, e flux.Event) error {
+			close(handling)
+			<-release
+			return nil
+		},
+		flux.WithConsumerPollInterval(time.Millisecond),
+	)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- consumer.Start(context.Background())
+	}()
+
+	<-handling
+
+	// The handler is still running, so the consumer cannot stop before the context expires.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, consumer.Shutdown(ctx), context.DeadlineExceeded)
+
+	// The consumer keeps shutting down in the background.
+	close(release)
+	require.NoError(t, <-errs)
+}
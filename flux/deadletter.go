@@ -0,0 +1,141 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrDeadLetterNotFound is an error that is returned when a dead letter cannot be found.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is an event which a Relay gave up dispatching after repeated failures. It implements the Event interface,
+// so that it can be passed to any Dispatcher.
+type DeadLetter struct {
+	Event
+
+	// The error returned by the final dispatch attempt.
+	Cause string
+
+	// The number of times the relay attempted to dispatch the event.
+	Attempts int
+
+	// The time at which the event was dead-lettered.
+	DeadLetteredAt time.Time
+}
+
+// Headers which carry the details of a DeadLetter, when it is dispatched by a dispatcher which sets message headers.
+const (
+	DeadLetterHeaderCause    = "Flux-Dead-Letter-Cause"
+	DeadLetterHeaderAttempts = "Flux-Dead-Letter-Attempts"
+	DeadLetterHeaderTime     = "Flux-Dead-Letter-Time"
+)
+
+// injectDeadLetter passes the details of the event to set as headers, if it is a DeadLetter.
+func injectDeadLetter(e Event, set func(key, value string)) {
+	letter, ok := e.(*DeadLetter)
+	if !ok {
+		return
+	}
+
+	// Header values cannot contain control characters such as newlines, which are common in error messages.
+	cause := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+
+		return r
+	}, letter.Cause)
+
+	set(DeadLetterHeaderCause, cause)
+	set(DeadLetterHeaderAttempts, strconv.Itoa(letter.Attempts))
+	set(DeadLetterHeaderTime, letter.DeadLetteredAt.Format(time.RFC3339Nano))
+}
+
+// extractDeadLetter returns the event as a DeadLetter if get returns the headers set by injectDeadLetter, or otherwise
+// returns the event as is.
+func extractDeadLetter(e Event, get func(key string) string) (Event, error) {
+	attempts := get(DeadLetterHeaderAttempts)
+	if attempts == "" {
+		return e, nil
+	}
+
+	letter := DeadLetter{
+		Event: e,
+		Cause: get(DeadLetterHeaderCause),
+	}
+
+	var err error
+	if letter.Attempts, err = strconv.Atoi(attempts); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", DeadLetterHeaderAttempts, err)
+	}
+
+	if at := get(DeadLetterHeaderTime); at != "" {
+		if letter.DeadLetteredAt, err = time.Parse(time.RFC3339Nano, at); err != nil {
+			return nil, fmt.Errorf("invalid %s header: %w", DeadLetterHeaderTime, err)
+		}
+	}
+
+	return &letter, nil
+}
+
+// DeadLetterSink receives the events which a Relay has given up dispatching.
+type DeadLetterSink interface {
+	// DeadLetter hands over an event that could not be dispatched. The relay only advances past the event once it
+	// returns nil.
+	DeadLetter(ctx context.Context, letter *DeadLetter) error
+}
+
+// DeadLetterStore is a DeadLetterSink which keeps dead letters so that they can be inspected and replayed.
+type DeadLetterStore interface {
+	DeadLetterSink
+
+	// ListDeadLetters returns up to limit dead letters, ordered by the sequence of their events.
+	ListDeadLetters(ctx context.Context, limit uint) ([]*DeadLetter, error)
+
+	// DeleteDeadLetter removes the dead letter for an event. It returns ErrDeadLetterNotFound if there is none.
+	DeleteDeadLetter(ctx context.Context, eventID string) error
+}
+
+// DeadLetterSinkFunc is a function type that implements the DeadLetterSink interface.
+type DeadLetterSinkFunc func(ctx context.Context, letter *DeadLetter) error
+
+// DeadLetter calls the function with the dead letter.
+func (f DeadLetterSinkFunc) DeadLetter(ctx context.Context, letter *DeadLetter) error {
+	return f(ctx, letter)
+}
+
+// DispatchDeadLetters returns a DeadLetterSink that passes dead letters to a dispatcher, such as one which publishes to
+// a dead-letter topic. The dispatcher receives a *DeadLetter, which carries the error and the number of attempts.
+//
+// The NATS, JetStream, Kafka and webhook dispatchers send these details in the headers named by the DeadLetterHeader
+// constants, and the gRPC dispatcher and ProtobufCodec in the dead-letter fields of the event. WebhookReceiver and
+// EventFromProto decode them as a *DeadLetter.
+func DispatchDeadLetters(d Dispatcher) DeadLetterSink {
+	return DeadLetterSinkFunc(func(ctx context.Context, letter *DeadLetter) error {
+		return d.Dispatch(ctx, letter)
+	})
+}
+
+// ReplayDeadLetters dispatches dead-lettered events through the relay's dispatcher, removing each from the store once
+// it has been dispatched. It stops at the first event that fails to dispatch, which is left in the store.
+//
+// Replayed events bypass the relay's stream, and so may be dispatched concurrently with, and out of order relative to,
+// the events being streamed.
+func (r *Relay) ReplayDeadLetters(ctx context.Context, store DeadLetterStore, letters ...*DeadLetter) error {
+	for _, letter := range letters {
+		if err := r.dispatcher.Dispatch(ctx, letter.Event); err != nil {
+			return fmt.Errorf("failed to replay event %s: %w", letter.ID(), err)
+		}
+
+		if err := store.DeleteDeadLetter(ctx, letter.ID()); err != nil {
+			return fmt.Errorf("failed to delete dead letter %s: %w", letter.ID(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,292 @@
+package flux
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrTopicDefined is an error that is returned when a topic is defined more than once in the same registry.
+	ErrTopicDefined = errors.New("topic already defined")
+
+	// ErrInvalidPayload is an error that is returned when the payload of an event cannot be decoded into the type of
+	// its definition.
+	ErrInvalidPayload = errors.New("invalid payload")
+)
+
+// PayloadCodec encodes and decodes the payloads of typed events.
+type PayloadCodec interface {
+	// ContentType returns the content type of the encoded payloads, e.g. "application/json".
+	ContentType() string
+
+	// Marshal encodes the value as a payload.
+	Marshal(v any) ([]byte, error)
+
+	// Unmarshal decodes the payload into the value. For protobuf messages, the value is the message itself, otherwise
+	// it is a pointer to the value.
+	Unmarshal(data []byte, v any) error
+}
+
+// Compile-time assertion that JSONPayloadCodec implements the PayloadCodec interface.
+var _ PayloadCodec = JSONPayloadCodec{}
+
+// JSONPayloadCodec is a PayloadCodec which encodes payloads as JSON. It is the default codec of a Definition.
+type JSONPayloadCodec struct{}
+
+// ContentType returns "application/json".
+func (JSONPayloadCodec) ContentType() string { return "application/json" }
+
+// Marshal encodes the value as JSON.
+func (JSONPayloadCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal decodes JSON into the value.
+func (JSONPayloadCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// Compile-time assertion that ProtobufPayloadCodec implements the PayloadCodec interface.
+var _ PayloadCodec = ProtobufPayloadCodec{}
+
+// ProtobufPayloadCodec is a PayloadCodec which encodes payloads as protobuf messages. It can only be used by
+// definitions whose type is a pointer to a protobuf message.
+type ProtobufPayloadCodec struct{}
+
+// ContentType returns "application/x-protobuf".
+func (ProtobufPayloadCodec) ContentType() string { return "application/x-protobuf" }
+
+// Marshal encodes the protobuf message.
+func (ProtobufPayloadCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", v)
+	}
+
+	return proto.Marshal(m)
+}
+
+// Unmarshal decodes the payload into the protobuf message.
+func (ProtobufPayloadCodec) Unmarshal(data []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a protobuf message", v)
+	}
+
+	return proto.Unmarshal(data, m)
+}
+
+// DefaultTopicRegistry is the registry that definitions are registered in, unless they are given another registry.
+var DefaultTopicRegistry = NewTopicRegistry()
+
+// NewTopicRegistry returns a new, empty TopicRegistry.
+func NewTopicRegistry() *TopicRegistry {
+	return &TopicRegistry{
+		topics:   make(map[EventTopic]bool),
+		handlers: make(map[EventTopic][]ConsumerFunc),
+	}
+}
+
+// TopicRegistry holds the topics of a set of definitions, and the handlers registered for them. Each topic may only be
+// defined once per registry. It is safe for concurrent use.
+//
+// A TopicRegistry is a Dispatcher, and its Dispatch method is a ConsumerFunc, so the handlers of its definitions can
+// be driven by a Relay or a Consumer.
+type TopicRegistry struct {
+	mu       sync.RWMutex
+	topics   map[EventTopic]bool
+	handlers map[EventTopic][]ConsumerFunc
+}
+
+// Compile-time assertion that TopicRegistry implements the Dispatcher interface.
+var _ Dispatcher = (*TopicRegistry)(nil)
+
+// define adds the topic to the registry. It returns ErrTopicDefined if the topic has already been defined.
+func (r *TopicRegistry) define(topic EventTopic) error {
+	if topic == "" {
+		return errors.New("topic must not be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.topics[topic] {
+		return fmt.Errorf("%w: %q", ErrTopicDefined, topic)
+	}
+
+	r.topics[topic] = true
+
+	return nil
+}
+
+// handle registers a handler for the events of the topic.
+func (r *TopicRegistry) handle(topic EventTopic, fn ConsumerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[topic] = append(r.handlers[topic], fn)
+}
+
+// Topics returns the topics defined in the registry, in lexical order.
+func (r *TopicRegistry) Topics() []EventTopic {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	topics := make([]EventTopic, 0, len(r.topics))
+	for topic := range r.topics {
+		topics = append(topics, topic)
+	}
+
+	sort.Slice(topics, func(i, j int) bool { return topics[i] < topics[j] })
+
+	return topics
+}
+
+// Dispatch passes the event to every handler registered for its topic, in the order they were registered, stopping at
+// the first error. Events of topics without handlers are ignored.
+func (r *TopicRegistry) Dispatch(ctx context.Context, e Event) error {
+	r.mu.RLock()
+	handlers := r.handlers[e.Topic()]
+	r.mu.RUnlock()
+
+	for _, fn := range handlers {
+		if err := fn(ctx, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DefinitionConfig holds the configuration of a Definition.
+type DefinitionConfig struct {
+	// The registry that the definition's topic and handlers are registered in.
+	Registry *TopicRegistry
+
+	// The codec which encodes and decodes the definition's payloads.
+	PayloadCodec PayloadCodec
+}
+
+// DefinitionOption is an interface that allows for functional options to be applied to a DefinitionConfig.
+type DefinitionOption interface {
+	Apply(*DefinitionConfig)
+}
+
+// DefinitionOptionFunc is a function type that implements the DefinitionOption interface.
+type DefinitionOptionFunc func(*DefinitionConfig)
+
+// Apply applies the function to the definition config.
+func (f DefinitionOptionFunc) Apply(config *DefinitionConfig) {
+	f(config)
+}
+
+// WithTopicRegistry sets the registry that the definition is registered in, in place of DefaultTopicRegistry.
+func WithTopicRegistry(registry *TopicRegistry) DefinitionOption {
+	return DefinitionOptionFunc(func(config *DefinitionConfig) {
+		config.Registry = registry
+	})
+}
+
+// WithPayloadCodec sets the codec which encodes and decodes the definition's payloads.
+func WithPayloadCodec(codec PayloadCodec) DefinitionOption {
+	return DefinitionOptionFunc(func(config *DefinitionConfig) {
+		config.PayloadCodec = codec
+	})
+}
+
+// Define returns the definition of the events of a topic, whose payloads are of type T. Definitions are meant to be
+// declared as package-level variables, e.g.
+//
+//	var UserCreated = flux.Define[User]("user.created")
+//
+// By default, the topic is registered in DefaultTopicRegistry and payloads are encoded as JSON. Define panics if the
+// topic has already been defined in the registry, so duplicate topics are caught when a program starts.
+func Define[T any](topic string, opts ...DefinitionOption) *Definition[T] {
+	config := DefinitionConfig{
+		Registry:     DefaultTopicRegistry,
+		PayloadCodec: JSONPayloadCodec{},
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	if err := config.Registry.define(EventTopic(topic)); err != nil {
+		panic(fmt.Errorf("flux: failed to define topic: %w", err))
+	}
+
+	return &Definition[T]{
+		topic:  EventTopic(topic),
+		config: &config,
+	}
+}
+
+// Definition is the type-safe definition of the events of a single topic, whose payloads are of type T.
+type Definition[T any] struct {
+	topic  EventTopic
+	config *DefinitionConfig
+}
+
+// Topic returns the topic of the definition's events.
+func (d *Definition[T]) Topic() EventTopic {
+	return d.topic
+}
+
+// Publish creates an event of the definition's topic in the event store, with the encoded payload.
+func (d *Definition[T]) Publish(
+	ctx context.Context,
+	writer EventWriter,
+	key string,
+	payload T,
+	opts ...EventOption,
+) (Event, error) {
+	data, err := d.config.PayloadCodec.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %q payload: %w", d.topic, err)
+	}
+
+	opts = append(opts, WithPayload(d.config.PayloadCodec.ContentType(), data))
+
+	return writer.CreateEvent(ctx, d.topic.String(), key, opts...)
+}
+
+// Decode decodes the payload of an event of the definition's topic. It returns ErrInvalidPayload if the event is of
+// another topic, or its payload cannot be decoded.
+func (d *Definition[T]) Decode(e Event) (T, error) {
+	var payload T
+
+	if e.Topic() != d.topic {
+		return payload, fmt.Errorf("%w: event %s is of topic %q, not %q", ErrInvalidPayload, e.ID(), e.Topic(), d.topic)
+	}
+
+	// Protobuf messages are pointers, which need to be allocated before they can be unmarshalled into.
+	if m, ok := any(payload).(proto.Message); ok {
+		payload = m.ProtoReflect().New().Interface().(T)
+		if err := d.config.PayloadCodec.Unmarshal(e.Payload(), payload); err != nil {
+			return payload, fmt.Errorf("%w: event %s: %w", ErrInvalidPayload, e.ID(), err)
+		}
+
+		return payload, nil
+	}
+
+	if err := d.config.PayloadCodec.Unmarshal(e.Payload(), &payload); err != nil {
+		return payload, fmt.Errorf("%w: event %s: %w", ErrInvalidPayload, e.ID(), err)
+	}
+
+	return payload, nil
+}
+
+// Handle registers a handler for the definition's events in its registry. The handler is passed each event along with
+// its decoded payload. An error is returned by the registry's Dispatch method if the payload cannot be decoded.
+func (d *Definition[T]) Handle(fn func(ctx context.Context, e Event, payload T) error) {
+	d.config.Registry.handle(d.topic, func(ctx context.Context, e Event) error {
+		payload, err := d.Decode(e)
+		if err != nil {
+			return err
+		}
+
+		return fn(ctx, e, payload)
+	})
+}
@@ -0,0 +1,176 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/nickcorin/toolkit/flux/fluxpb"
+	"github.com/stretchr/testify/require"
+)
+
+type userCreated struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestDefinition(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("publish and handle", func(t *testing.T) {
+		registry := flux.NewTopicRegistry()
+		store := flux.NewMemoryEventStore()
+
+		def := flux.Define[userCreated]("user.created", flux.WithTopicRegistry(registry))
+		require.Equal(t, flux.EventTopic("user.created"), def.Topic())
+
+		var handled []userCreated
+		def.Handle(func(ctx context.Context, e flux.Event, payload userCreated) error {
+			require.Equal(t, "user-1", e.Key())
+			handled = append(handled, payload)
+			return nil
+		})
+
+		payload := userCreated{Name: "Test", Email: "test@example.com"}
+
+		e, err := def.Publish(ctx, store, "user-1", payload)
+		require.NoError(t, err)
+		require.Equal(t, def.Topic(), e.Topic())
+		require.Equal(t, "application/json", e.ContentType())
+
+		require.NoError(t, registry.Dispatch(ctx, e))
+		require.Equal(t, []userCreated{payload}, handled)
+	})
+
+	t.Run("events are routed by topic", func(t *testing.T) {
+		registry := flux.NewTopicRegistry()
+		store := flux.NewMemoryEventStore()
+
+		created := flux.Define[userCreated]("user.created", flux.WithTopicRegistry(registry))
+		deleted := flux.Define[string]("user.deleted", flux.WithTopicRegistry(registry))
+
+		var handled []string
+		created.Handle(func(ctx context.Context, e flux.Event, payload userCreated) error {
+			handled = append(handled, "created "+payload.Name)
+			return nil
+		})
+		deleted.Handle(func(ctx context.Context, e flux.Event, payload string) error {
+			handled = append(handled, "deleted "+payload)
+			return nil
+		})
+
+		e1, err := created.Publish(ctx, store, "user-1", userCreated{Name: "Test"})
+		require.NoError(t, err)
+
+		e2, err := deleted.Publish(ctx, store, "user-1", "Test")
+		require.NoError(t, err)
+
+		e3, err := store.CreateEvent(ctx, "other.topic", "user-1")
+		require.NoError(t, err)
+
+		for _, e := range []flux.Event{e1, e2, e3} {
+			require.NoError(t, registry.Dispatch(ctx, e))
+		}
+
+		require.Equal(t, []string{"created Test", "deleted Test"}, handled)
+		require.Equal(t, []flux.EventTopic{"user.created", "user.deleted"}, registry.Topics())
+	})
+
+	t.Run("handler errors are returned", func(t *testing.T) {
+		registry := flux.NewTopicRegistry()
+		store := flux.NewMemoryEventStore()
+
+		def := flux.Define[userCreated]("user.created", flux.WithTopicRegistry(registry))
+
+		testErr := errors.New("test error")
+		def.Handle(func(ctx context.Context, e flux.Event, payload userCreated) error {
+			return testErr
+		})
+
+		e, err := def.Publish(ctx, store, "user-1", userCreated{})
+		require.NoError(t, err)
+		require.ErrorIs(t, registry.Dispatch(ctx, e), testErr)
+	})
+
+	t.Run("invalid payloads", func(t *testing.T) {
+		registry := flux.NewTopicRegistry()
+		store := flux.NewMemoryEventStore()
+
+		def := flux.Define[userCreated]("user.created", flux.WithTopicRegistry(registry))
+		def.Handle(func(ctx context.Context, e flux.Event, payload userCreated) error {
+			return nil
+		})
+
+		e, err := store.CreateEvent(ctx, "user.created", "user-1", flux.WithPayload("text/plain", []byte("test")))
+		require.NoError(t, err)
+		require.ErrorIs(t, registry.Dispatch(ctx, e), flux.ErrInvalidPayload)
+
+		other, err := store.CreateEvent(ctx, "other.topic", "user-1")
+		require.NoError(t, err)
+
+		_, err = def.Decode(other)
+		require.ErrorIs(t, err, flux.ErrInvalidPayload)
+	})
+
+	t.Run("protobuf payloads", func(t *testing.T) {
+		registry := flux.NewTopicRegistry()
+		store := flux.NewMemoryEventStore()
+
+		def := flux.Define[*fluxpb.Event](
+			"event.forwarded",
+			flux.WithTopicRegistry(registry),
+			flux.WithPayloadCodec(flux.ProtobufPayloadCodec{}),
+		)
+
+		payload := &fluxpb.Event{Id: "test-id", Topic: "test-topic"}
+
+		e, err := def.Publish(ctx, store, "test-key", payload)
+		require.NoError(t, err)
+		require.Equal(t, "application/x-protobuf", e.ContentType())
+
+		got, err := def.Decode(e)
+		require.NoError(t, err)
+		require.Equal(t, "test-id", got.Id)
+		require.Equal(t, "test-topic", got.Topic)
+	})
+
+	t.Run("duplicate topics are rejected", func(t *testing.T) {
+		registry := flux.NewTopicRegistry()
+		flux.Define[userCreated]("user.created", flux.WithTopicRegistry(registry))
+
+		defer func() {
+			err, ok := recover().(error)
+			require.True(t, ok)
+			require.ErrorIs(t, err, flux.ErrTopicDefined)
+		}()
+
+		flux.Define[string]("user.created", flux.WithTopicRegistry(registry))
+		t.Fatal("expected Define to panic")
+	})
+
+	t.Run("drives a consumer", func(t *testing.T) {
+		registry := flux.NewTopicRegistry()
+		store := flux.NewMemoryEventStore()
+
+		def := flux.Define[userCreated]("user.created", flux.WithTopicRegistry(registry))
+
+		consumer := flux.NewConsumer("test-consumer", store, flux.NewMemoryCursorStore(), registry.Dispatch)
+
+		handled := make(chan userCreated, 1)
+		def.Handle(func(ctx context.Context, e flux.Event, payload userCreated) error {
+			handled <- payload
+
+			// Shutdown waits for the handler to return, so it must not be called from within it.
+			go func() { _ = consumer.Shutdown(context.Background()) }()
+
+			return nil
+		})
+
+		_, err := def.Publish(ctx, store, "user-1", userCreated{Name: "Test"})
+		require.NoError(t, err)
+
+		require.NoError(t, consumer.Start(ctx))
+		require.Equal(t, userCreated{Name: "Test"}, <-handled)
+	})
+}
@@ -3,6 +3,7 @@ package flux
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nickcorin/toolkit/flux/fluxpb"
@@ -13,6 +14,14 @@ type Dispatcher interface {
 	Dispatch(ctx context.Context, e Event) error
 }
 
+// DispatcherFunc is a function type that implements the Dispatcher interface.
+type DispatcherFunc func(ctx context.Context, e Event) error
+
+// Dispatch calls the function with the event.
+func (f DispatcherFunc) Dispatch(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
 // Compile-time assertion that GRPCDispatcher implements the Dispatcher interface.
 var _ Dispatcher = (*GRPCDispatcher)(nil)
 
@@ -23,13 +32,25 @@ func NewGRPCDispatcher(conn fluxpb.Flux_DispatchServer) *GRPCDispatcher {
 	}
 }
 
-// GRPCDispatcher is a dispatcher that sends events to a gRPC server.
+// GRPCDispatcher is a dispatcher that sends events to a gRPC server. It is safe for concurrent use.
 type GRPCDispatcher struct {
+	// gRPC does not allow messages to be sent on the same stream concurrently, so sends are serialised.
+	mu   sync.Mutex
 	conn fluxpb.Flux_DispatchServer
 }
 
+// Dispatch sends the event to the client. A server stream cannot attach metadata to individual messages, so the trace
+// context of the dispatch is sent in the event's trace_parent field, in place of the one the event was created with.
 func (d *GRPCDispatcher) Dispatch(ctx context.Context, e Event) error {
-	return d.conn.Send(EventToProto(e))
+	pb := EventToProto(e)
+	if traceParent := traceParentFromContext(ctx); traceParent != "" {
+		pb.TraceParent = traceParent
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.conn.Send(pb)
 }
 
 // Compile-time assertion that NatsDispatcher implements the Dispatcher interface.
@@ -49,13 +70,21 @@ type NatsDispatcher struct {
 	conn  *nats.Conn
 }
 
+// Dispatch publishes the event, with the trace context of the dispatch in the message's headers. If the codec is a
+// HeaderCodec, it may set headers of its own. The details of a *DeadLetter are also sent as headers.
 func (d *NatsDispatcher) Dispatch(ctx context.Context, e Event) error {
-	msg, err := d.codec.Encode(e)
+	msg := nats.NewMsg(e.Topic().String())
+
+	data, err := encodeMessage(d.codec, e, msg.Header)
 	if err != nil {
 		return fmt.Errorf("failed to encode event: %w", err)
 	}
 
-	if err := d.conn.Publish(e.Topic().String(), msg); err != nil {
+	msg.Data = data
+	injectDeadLetter(e, msg.Header.Set)
+	injectTraceContext(ctx, msg.Header.Set)
+
+	if err := d.conn.PublishMsg(msg); err != nil {
 		return fmt.Errorf("failed to dispatch event: %w", err)
 	}
 
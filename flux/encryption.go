@@ -0,0 +1,257 @@
+package flux
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrKeyNotFound is an error that is returned when a KeyProvider does not have a key with the requested id.
+	ErrKeyNotFound = errors.New("key not found")
+
+	// ErrInvalidCiphertext is an error that is returned when data cannot be decrypted by an EncryptionCodec.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// KeyProvider provides the key encryption keys of an EncryptionCodec. Keys must be 16, 24 or 32 bytes long, to select
+// AES-128, AES-192 or AES-256.
+//
+// Keys are identified by an id which is recorded alongside the data they encrypt, so keys can be rotated by changing
+// the current key, as long as older keys can still be looked up by their id.
+type KeyProvider interface {
+	// CurrentKey returns the key which new data is encrypted with, along with its id.
+	CurrentKey() (id string, key []byte, err error)
+
+	// Key returns the key with the given id, or ErrKeyNotFound if there is no such key.
+	Key(id string) ([]byte, error)
+}
+
+// Compile-time assertion that KeyRing implements the KeyProvider interface.
+var _ KeyProvider = (*KeyRing)(nil)
+
+// NewKeyRing returns a KeyRing whose current key is the given key.
+func NewKeyRing(id string, key []byte) *KeyRing {
+	return &KeyRing{
+		current: id,
+		keys:    map[string][]byte{id: key},
+	}
+}
+
+// KeyRing is an in-memory KeyProvider which keeps every key it has held, so data encrypted with a key which has since
+// been rotated can still be decrypted. It is safe for concurrent use.
+type KeyRing struct {
+	mu      sync.RWMutex
+	current string
+	keys    map[string][]byte
+}
+
+// Add adds a key which data may have been encrypted with, without changing the current key.
+func (r *KeyRing) Add(id string, key []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[id] = key
+}
+
+// Rotate adds a key and makes it the current key. Keys which were previously current are kept for decryption.
+func (r *KeyRing) Rotate(id string, key []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.keys[id] = key
+	r.current = id
+}
+
+// CurrentKey returns the current key and its id.
+func (r *KeyRing) CurrentKey() (string, []byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.current, r.keys[r.current], nil
+}
+
+// Key returns the key with the given id.
+func (r *KeyRing) Key(id string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+
+	return key, nil
+}
+
+// encryptionVersion is the first byte of encrypted data, which identifies its layout.
+const encryptionVersion = 1
+
+// dataKeySize is the size of the data keys generated for each event, which are used with AES-256.
+const dataKeySize = 32
+
+// Compile-time assertion that EncryptionCodec implements the Codec interface.
+var _ Codec = (*EncryptionCodec)(nil)
+
+// NewEncryptionCodec returns a codec which encrypts the data of another codec using AES-GCM envelope encryption.
+//
+// Every event is encrypted with a new, random data key, which is itself encrypted with the current key of the key
+// provider and stored alongside the event. The id of that key is recorded too, so events encrypted with older keys are
+// decrypted with the key they were encrypted with.
+func NewEncryptionCodec(codec Codec, keys KeyProvider) *EncryptionCodec {
+	return &EncryptionCodec{
+		codec: codec,
+		keys:  keys,
+	}
+}
+
+// EncryptionCodec is a Codec which encrypts the data of another codec.
+type EncryptionCodec struct {
+	codec Codec
+	keys  KeyProvider
+}
+
+// Encode encodes the event using the inner codec and encrypts the data.
+//
+// The encrypted data is laid out as the version of the layout, the length of the key id and the key id itself, the
+// encrypted data key and finally the encrypted event. Everything before the encrypted event is authenticated along
+// with it.
+func (codec *EncryptionCodec) Encode(e Event) ([]byte, error) {
+	data, err := codec.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+
+	id, key, err := codec.keys.CurrentKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current key: %w", err)
+	}
+
+	if len(id) > 255 {
+		return nil, fmt.Errorf("key id must be at most 255 bytes long: %q", id)
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	header := make([]byte, 0, 2+len(id))
+	header = append(header, encryptionVersion, byte(len(id)))
+	header = append(header, id...)
+
+	// The key id is authenticated along with the data key, so the data key cannot be moved under another key id.
+	wrappedKey, err := seal(key, dataKey, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt data key: %w", err)
+	}
+
+	header = append(header, wrappedKey...)
+
+	ciphertext, err := seal(dataKey, data, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt event: %w", err)
+	}
+
+	return append(header, ciphertext...), nil
+}
+
+// Decode decrypts the data using the key it was encrypted with, and decodes it using the inner codec.
+func (codec *EncryptionCodec) Decode(data []byte) (Event, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidCiphertext)
+	}
+
+	if data[0] != encryptionVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidCiphertext, data[0])
+	}
+
+	n := 2 + int(data[1])
+	if len(data) < n {
+		return nil, fmt.Errorf("%w: truncated key id", ErrInvalidCiphertext)
+	}
+
+	id := string(data[2:n])
+
+	key, err := codec.keys.Key(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key %q: %w", id, err)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The wrapped data key is made up of its nonce, the encrypted key and the authentication tag.
+	end := n + gcm.NonceSize() + dataKeySize + gcm.Overhead()
+	if len(data) < end {
+		return nil, fmt.Errorf("%w: truncated data key", ErrInvalidCiphertext)
+	}
+
+	dataKey, err := open(gcm, data[n:end], data[:n])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+
+	dataGCM, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := open(dataGCM, data[end:], data[:end])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt event: %w", err)
+	}
+
+	return codec.codec.Decode(plaintext)
+}
+
+// newGCM returns an AES-GCM cipher using the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return gcm, nil
+}
+
+// seal encrypts and authenticates the plaintext with a random nonce, which prefixes the returned ciphertext.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts and authenticates a ciphertext returned by seal.
+func open(gcm cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: truncated nonce", ErrInvalidCiphertext)
+	}
+
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCiphertext, err)
+	}
+
+	return plaintext, nil
+}
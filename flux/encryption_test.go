@@ -0,0 +1,143 @@
+package flux_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) []byte {
+	t.Helper()
+
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	return key
+}
+
+func TestEncryptionCodec(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	t.Run("round trip", func(t *testing.T) {
+		codec := flux.NewEncryptionCodec(flux.NewProtobufCodec(), flux.NewKeyRing("key-1", newKey(t)))
+
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = []byte("secret payload")
+
+		data, err := codec.Encode(e)
+		require.NoError(t, err)
+		require.NotContains(t, string(data), "secret payload")
+
+		got, err := codec.Decode(data)
+		require.NoError(t, err)
+		requireDecodedEventEqual(t, e, got)
+	})
+
+	t.Run("every event uses a new data key", func(t *testing.T) {
+		codec := flux.NewEncryptionCodec(flux.NewJSONCodec(), flux.NewKeyRing("key-1", newKey(t)))
+		e := generator.generateRandomEvent()
+
+		first, err := codec.Encode(e)
+		require.NoError(t, err)
+
+		second, err := codec.Encode(e)
+		require.NoError(t, err)
+		require.NotEqual(t, first, second)
+	})
+
+	t.Run("key rotation", func(t *testing.T) {
+		keys := flux.NewKeyRing("key-1", newKey(t))
+		codec := flux.NewEncryptionCodec(flux.NewJSONCodec(), keys)
+
+		old := generator.generateRandomEvent()
+		oldData, err := codec.Encode(old)
+		require.NoError(t, err)
+
+		keys.Rotate("key-2", newKey(t))
+
+		e := generator.generateRandomEvent()
+		data, err := codec.Encode(e)
+		require.NoError(t, err)
+
+		got, err := codec.Decode(oldData)
+		require.NoError(t, err)
+		requireDecodedEventEqual(t, old, got)
+
+		got, err = codec.Decode(data)
+		require.NoError(t, err)
+		requireDecodedEventEqual(t, e, got)
+
+		// A consumer which has only been given the new key cannot decrypt older events.
+		rotated := flux.NewEncryptionCodec(flux.NewJSONCodec(), flux.NewKeyRing("key-2", mustKey(t, keys, "key-2")))
+
+		_, err = rotated.Decode(data)
+		require.NoError(t, err)
+
+		_, err = rotated.Decode(oldData)
+		require.ErrorIs(t, err, flux.ErrKeyNotFound)
+	})
+
+	t.Run("composes with compression", func(t *testing.T) {
+		codec := flux.NewEncryptionCodec(
+			flux.NewCompressionCodec(flux.NewJSONCodec(), flux.CompressionZstd),
+			flux.NewKeyRing("key-1", newKey(t)),
+		)
+
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = bytes.Repeat([]byte("test"), 1000)
+
+		data, err := codec.Encode(e)
+		require.NoError(t, err)
+		require.Less(t, len(data), len(e.payload))
+
+		got, err := codec.Decode(data)
+		require.NoError(t, err)
+		requireDecodedEventEqual(t, e, got)
+	})
+
+	t.Run("tampered data", func(t *testing.T) {
+		codec := flux.NewEncryptionCodec(flux.NewJSONCodec(), flux.NewKeyRing("key-1", newKey(t)))
+
+		data, err := codec.Encode(generator.generateRandomEvent())
+		require.NoError(t, err)
+
+		tests := map[string]func([]byte) []byte{
+			"empty":            func([]byte) []byte { return nil },
+			"truncated key id": func(data []byte) []byte { return data[:4] },
+			"truncated key":    func(data []byte) []byte { return data[:20] },
+			"flipped bit": func(data []byte) []byte {
+				data[len(data)-1] ^= 1
+				return data
+			},
+		}
+
+		for name, tamper := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := codec.Decode(tamper(bytes.Clone(data)))
+				require.ErrorIs(t, err, flux.ErrInvalidCiphertext)
+			})
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		data, err := flux.NewEncryptionCodec(flux.NewJSONCodec(), flux.NewKeyRing("key-1", newKey(t))).
+			Encode(generator.generateRandomEvent())
+		require.NoError(t, err)
+
+		_, err = flux.NewEncryptionCodec(flux.NewJSONCodec(), flux.NewKeyRing("key-1", newKey(t))).Decode(data)
+		require.ErrorIs(t, err, flux.ErrInvalidCiphertext)
+	})
+}
+
+func mustKey(t *testing.T, keys flux.KeyProvider, id string) []byte {
+	t.Helper()
+
+	key, err := keys.Key(id)
+	require.NoError(t, err)
+
+	return key
+}
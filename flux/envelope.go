@@ -0,0 +1,340 @@
+package flux
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrInvalidEnvelope is an error that is returned when data cannot be decoded as an envelope.
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+
+	// ErrUnknownFormat is an error that is returned when an envelope's format has not been registered.
+	ErrUnknownFormat = errors.New("unknown format")
+
+	// ErrFormatRegistered is an error that is returned when a format is registered more than once.
+	ErrFormatRegistered = errors.New("format already registered")
+
+	// ErrUnsupportedSchemaVersion is an error that is returned when an envelope's schema version is newer than the
+	// registered version of its format, or an older version cannot be migrated to it.
+	ErrUnsupportedSchemaVersion = errors.New("unsupported schema version")
+)
+
+// envelopeMagic prefixes every envelope, followed by the version of the envelope layout.
+var envelopeMagic = []byte("FX")
+
+const envelopeVersion = 1
+
+// MigrationFunc upgrades the encoded data of an event by a single schema version.
+type MigrationFunc func(data []byte) ([]byte, error)
+
+// NewCodecRegistry returns a new, empty CodecRegistry.
+func NewCodecRegistry(opts ...CodecRegistryOption) *CodecRegistry {
+	config := CodecRegistryConfig{
+		MaxDecompressedSize: DefaultMaxDecompressedSize,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &CodecRegistry{
+		formats:      make(map[string]*registeredFormat),
+		config:       &config,
+		decompressor: newDecompressor(config.MaxDecompressedSize),
+	}
+}
+
+// CodecRegistry holds the codecs which may have encoded an envelope, keyed by format id, along with the current schema
+// version of each format and the migrations which upgrade data from older versions. It is safe for concurrent use.
+type CodecRegistry struct {
+	mu           sync.RWMutex
+	formats      map[string]*registeredFormat
+	config       *CodecRegistryConfig
+	decompressor *decompressor
+}
+
+// CodecRegistryConfig holds the configuration of a CodecRegistry.
+type CodecRegistryConfig struct {
+	// The maximum size, in bytes, that the body of a compressed envelope may decompress to.
+	MaxDecompressedSize int
+}
+
+// CodecRegistryOption is an interface that allows for functional options to be applied to a CodecRegistryConfig.
+type CodecRegistryOption interface {
+	Apply(*CodecRegistryConfig)
+}
+
+// CodecRegistryOptionFunc is a function type that implements the CodecRegistryOption interface.
+type CodecRegistryOptionFunc func(*CodecRegistryConfig)
+
+// Apply applies the function to the registry config.
+func (f CodecRegistryOptionFunc) Apply(config *CodecRegistryConfig) {
+	f(config)
+}
+
+// WithEnvelopeMaxDecompressedSize sets the maximum size, in bytes, that the body of a compressed envelope may
+// decompress to. Envelopes which exceed it fail to decode with ErrDecompressedTooLarge.
+func WithEnvelopeMaxDecompressedSize(size int) CodecRegistryOption {
+	return CodecRegistryOptionFunc(func(config *CodecRegistryConfig) {
+		config.MaxDecompressedSize = size
+	})
+}
+
+type registeredFormat struct {
+	codec      Codec
+	version    uint
+	migrations map[uint]MigrationFunc
+}
+
+// Register registers the codec for the format, with the schema version of the data it encodes and decodes. It returns
+// ErrFormatRegistered if the format has already been registered.
+func (r *CodecRegistry) Register(format string, version uint, codec Codec) error {
+	if format == "" || len(format) > 255 {
+		return fmt.Errorf("format id must be between 1 and 255 bytes long: %q", format)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.formats[format]; ok {
+		return fmt.Errorf("%w: %q", ErrFormatRegistered, format)
+	}
+
+	r.formats[format] = &registeredFormat{
+		codec:      codec,
+		version:    version,
+		migrations: make(map[uint]MigrationFunc),
+	}
+
+	return nil
+}
+
+// RegisterMigration registers a function which upgrades data of the format from the given schema version to the next.
+// Data which is older than the format's registered version is upgraded one version at a time before it is decoded, so
+// there must be a migration from every older version which may still be read.
+func (r *CodecRegistry) RegisterMigration(format string, from uint, fn MigrationFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, ok := r.formats[format]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	if from >= f.version {
+		return fmt.Errorf("migration from version %d of %q is not older than version %d", from, format, f.version)
+	}
+
+	f.migrations[from] = fn
+
+	return nil
+}
+
+// Decode decodes an envelope using the codec registered for its format, after decompressing it and migrating it to the
+// format's registered schema version.
+func (r *CodecRegistry) Decode(data []byte) (Event, error) {
+	env, err := parseEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.RLock()
+	f, ok := r.formats[env.format]
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, env.format)
+	}
+
+	if env.version > f.version {
+		return nil, fmt.Errorf("%w: version %d of %q is newer than version %d",
+			ErrUnsupportedSchemaVersion, env.version, env.format, f.version)
+	}
+
+	body, err := r.decompressor.decompress(env.compression, env.body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress envelope: %w", err)
+	}
+
+	for version := env.version; version < f.version; version++ {
+		r.mu.RLock()
+		migrate, ok := f.migrations[version]
+		r.mu.RUnlock()
+
+		if !ok {
+			return nil, fmt.Errorf("%w: no migration from version %d of %q",
+				ErrUnsupportedSchemaVersion, version, env.format)
+		}
+
+		if body, err = migrate(body); err != nil {
+			return nil, fmt.Errorf("failed to migrate version %d of %q: %w", version, env.format, err)
+		}
+	}
+
+	return f.codec.Decode(body)
+}
+
+// Compile-time assertion that EnvelopeCodec implements the Codec interface.
+var _ Codec = (*EnvelopeCodec)(nil)
+
+// NewEnvelopeCodec returns a codec which encodes events using the codec registered for the format, and wraps them in an
+// envelope that records the format, its schema version and the compression of the data. It returns ErrUnknownFormat
+// if the format has not been registered.
+//
+// The codec decodes envelopes of any format in the registry, so consumers do not need to know how events were encoded.
+func NewEnvelopeCodec(registry *CodecRegistry, format string, opts ...EnvelopeCodecOption) (*EnvelopeCodec, error) {
+	registry.mu.RLock()
+	_, ok := registry.formats[format]
+	registry.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+
+	config := EnvelopeCodecConfig{
+		Compression: CompressionNone,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &EnvelopeCodec{
+		registry: registry,
+		format:   format,
+		config:   &config,
+	}, nil
+}
+
+// EnvelopeCodec is a Codec which wraps the data of another codec in a self-describing envelope.
+type EnvelopeCodec struct {
+	registry *CodecRegistry
+	format   string
+	config   *EnvelopeCodecConfig
+}
+
+// EnvelopeCodecConfig holds the configuration of an EnvelopeCodec.
+type EnvelopeCodecConfig struct {
+	// The algorithm used to compress the data of the inner codec.
+	Compression Compression
+}
+
+// EnvelopeCodecOption is an interface that allows for functional options to be applied to an EnvelopeCodecConfig.
+type EnvelopeCodecOption interface {
+	Apply(*EnvelopeCodecConfig)
+}
+
+// EnvelopeCodecOptionFunc is a function type that implements the EnvelopeCodecOption interface.
+type EnvelopeCodecOptionFunc func(*EnvelopeCodecConfig)
+
+// Apply applies the function to the codec config.
+func (f EnvelopeCodecOptionFunc) Apply(config *EnvelopeCodecConfig) {
+	f(config)
+}
+
+// WithEnvelopeCompression sets the algorithm used to compress the data of the inner codec.
+func WithEnvelopeCompression(compression Compression) EnvelopeCodecOption {
+	return EnvelopeCodecOptionFunc(func(config *EnvelopeCodecConfig) {
+		config.Compression = compression
+	})
+}
+
+// Encode encodes the event using the format's codec, at the format's registered schema version.
+func (codec *EnvelopeCodec) Encode(e Event) ([]byte, error) {
+	codec.registry.mu.RLock()
+	f := codec.registry.formats[codec.format]
+	codec.registry.mu.RUnlock()
+
+	data, err := f.codec.Encode(e)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := codec.config.Compression.compress(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress envelope: %w", err)
+	}
+
+	env := envelope{
+		format:      codec.format,
+		version:     f.version,
+		compression: codec.config.Compression,
+		body:        body,
+	}
+
+	return env.marshal(), nil
+}
+
+// Decode decodes an envelope of any format in the codec's registry.
+func (codec *EnvelopeCodec) Decode(data []byte) (Event, error) {
+	return codec.registry.Decode(data)
+}
+
+// envelope is the self-describing wrapper of encoded data. It is laid out as the magic bytes, the envelope version,
+// the length of the format id and the format id itself, the schema version as a uvarint, the compression and finally
+// the body.
+type envelope struct {
+	format      string
+	version     uint
+	compression Compression
+	body        []byte
+}
+
+func (env *envelope) marshal() []byte {
+	buf := make([]byte, 0, len(envelopeMagic)+2+len(env.format)+binary.MaxVarintLen64+1+len(env.body))
+
+	buf = append(buf, envelopeMagic...)
+	buf = append(buf, envelopeVersion, byte(len(env.format)))
+	buf = append(buf, env.format...)
+	buf = binary.AppendUvarint(buf, uint64(env.version))
+	buf = append(buf, byte(env.compression))
+	buf = append(buf, env.body...)
+
+	return buf
+}
+
+func parseEnvelope(data []byte) (*envelope, error) {
+	if !bytes.HasPrefix(data, envelopeMagic) {
+		return nil, fmt.Errorf("%w: missing magic bytes", ErrInvalidEnvelope)
+	}
+
+	data = data[len(envelopeMagic):]
+	if len(data) < 2 {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidEnvelope)
+	}
+
+	if data[0] != envelopeVersion {
+		return nil, fmt.Errorf("%w: unsupported envelope version %d", ErrInvalidEnvelope, data[0])
+	}
+
+	n := int(data[1])
+	data = data[2:]
+
+	if len(data) < n {
+		return nil, fmt.Errorf("%w: truncated format", ErrInvalidEnvelope)
+	}
+
+	env := envelope{format: string(data[:n])}
+	data = data[n:]
+
+	version, size := binary.Uvarint(data)
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: invalid schema version", ErrInvalidEnvelope)
+	}
+
+	env.version = uint(version)
+	data = data[size:]
+
+	if len(data) < 1 {
+		return nil, fmt.Errorf("%w: truncated header", ErrInvalidEnvelope)
+	}
+
+	env.compression = Compression(data[0])
+	env.body = data[1:]
+
+	return &env, nil
+}
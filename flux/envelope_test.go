@@ -0,0 +1,201 @@
+package flux_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopeCodec(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	registry := flux.NewCodecRegistry()
+	require.NoError(t, registry.Register("json", 1, flux.NewJSONCodec()))
+	require.NoError(t, registry.Register("protobuf", 1, flux.NewProtobufCodec()))
+
+	t.Run("decodes any registered format", func(t *testing.T) {
+		jsonCodec, err := flux.NewEnvelopeCodec(registry, "json")
+		require.NoError(t, err)
+
+		protobufCodec, err := flux.NewEnvelopeCodec(
+			registry,
+			"protobuf",
+			flux.WithEnvelopeCompression(flux.CompressionGzip),
+		)
+		require.NoError(t, err)
+
+		for _, encoder := range []flux.Codec{jsonCodec, protobufCodec} {
+			e := generator.generateEvent("test-topic", "test-key")
+			e.payload = bytes.Repeat([]byte("test"), 100)
+			e.contentType = "text/plain"
+
+			data, err := encoder.Encode(e)
+			require.NoError(t, err)
+
+			for _, decode := range []func([]byte) (flux.Event, error){
+				jsonCodec.Decode, protobufCodec.Decode, registry.Decode,
+			} {
+				got, err := decode(data)
+				require.NoError(t, err)
+
+				require.Equal(t, e.ID(), got.ID())
+				require.Equal(t, e.Topic(), got.Topic())
+				require.Equal(t, e.Sequence(), got.Sequence())
+				require.Equal(t, e.Payload(), got.Payload())
+				require.True(t, e.Timestamp().Equal(got.Timestamp()))
+			}
+		}
+	})
+
+	t.Run("compression", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = bytes.Repeat([]byte("test"), 1000)
+
+		plain, err := flux.NewEnvelopeCodec(registry, "json")
+		require.NoError(t, err)
+
+		compressed, err := flux.NewEnvelopeCodec(registry, "json", flux.WithEnvelopeCompression(flux.CompressionGzip))
+		require.NoError(t, err)
+
+		plainData, err := plain.Encode(e)
+		require.NoError(t, err)
+
+		compressedData, err := compressed.Encode(e)
+		require.NoError(t, err)
+		require.Less(t, len(compressedData), len(plainData))
+	})
+
+	t.Run("max decompressed size", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = bytes.Repeat([]byte("test"), 1000)
+
+		limited := flux.NewCodecRegistry(flux.WithEnvelopeMaxDecompressedSize(1000))
+		require.NoError(t, limited.Register("json", 1, flux.NewJSONCodec()))
+
+		codec, err := flux.NewEnvelopeCodec(limited, "json", flux.WithEnvelopeCompression(flux.CompressionGzip))
+		require.NoError(t, err)
+
+		data, err := codec.Encode(e)
+		require.NoError(t, err)
+
+		_, err = codec.Decode(data)
+		require.ErrorIs(t, err, flux.ErrDecompressedTooLarge)
+
+		// The default maximum is far larger than the event.
+		_, err = registry.Decode(data)
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		_, err := flux.NewEnvelopeCodec(registry, "xml")
+		require.ErrorIs(t, err, flux.ErrUnknownFormat)
+
+		other := flux.NewCodecRegistry()
+		require.NoError(t, other.Register("xml", 1, flux.NewJSONCodec()))
+
+		codec, err := flux.NewEnvelopeCodec(other, "xml")
+		require.NoError(t, err)
+
+		data, err := codec.Encode(generator.generateRandomEvent())
+		require.NoError(t, err)
+
+		_, err = registry.Decode(data)
+		require.ErrorIs(t, err, flux.ErrUnknownFormat)
+	})
+
+	t.Run("duplicate format", func(t *testing.T) {
+		err := registry.Register("json", 2, flux.NewJSONCodec())
+		require.ErrorIs(t, err, flux.ErrFormatRegistered)
+	})
+
+	t.Run("invalid envelopes", func(t *testing.T) {
+		codec, err := flux.NewEnvelopeCodec(registry, "json")
+		require.NoError(t, err)
+
+		data, err := codec.Encode(generator.generateRandomEvent())
+		require.NoError(t, err)
+
+		tests := map[string][]byte{
+			"empty":            nil,
+			"bare json":        []byte(`{"id":"test"}`),
+			"truncated header": data[:4],
+			"truncated format": data[:5],
+		}
+
+		for name, data := range tests {
+			t.Run(name, func(t *testing.T) {
+				_, err := registry.Decode(data)
+				require.ErrorIs(t, err, flux.ErrInvalidEnvelope)
+			})
+		}
+	})
+}
+
+func TestCodecRegistry_Migrations(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	// Encode an event using the first schema version of the format.
+	old := flux.NewCodecRegistry()
+	require.NoError(t, old.Register("events", 1, flux.NewJSONCodec()))
+
+	codec, err := flux.NewEnvelopeCodec(old, "events", flux.WithEnvelopeCompression(flux.CompressionGzip))
+	require.NoError(t, err)
+
+	data, err := codec.Encode(generator.generateEvent("old-topic", "test-key"))
+	require.NoError(t, err)
+
+	var applied []string
+	rename := func(name, from, to string) flux.MigrationFunc {
+		return func(data []byte) ([]byte, error) {
+			applied = append(applied, name)
+			return bytes.ReplaceAll(data, []byte(from), []byte(to)), nil
+		}
+	}
+
+	t.Run("older versions are migrated in order", func(t *testing.T) {
+		applied = nil
+
+		registry := flux.NewCodecRegistry()
+		require.NoError(t, registry.Register("events", 3, flux.NewJSONCodec()))
+		require.NoError(t, registry.RegisterMigration("events", 2, rename("v2", "new-topic", "newer-topic")))
+		require.NoError(t, registry.RegisterMigration("events", 1, rename("v1", "old-topic", "new-topic")))
+
+		e, err := registry.Decode(data)
+		require.NoError(t, err)
+		require.Equal(t, flux.EventTopic("newer-topic"), e.Topic())
+		require.Equal(t, []string{"v1", "v2"}, applied)
+	})
+
+	t.Run("missing migration", func(t *testing.T) {
+		registry := flux.NewCodecRegistry()
+		require.NoError(t, registry.Register("events", 3, flux.NewJSONCodec()))
+		require.NoError(t, registry.RegisterMigration("events", 2, rename("v2", "new-topic", "newer-topic")))
+
+		_, err := registry.Decode(data)
+		require.ErrorIs(t, err, flux.ErrUnsupportedSchemaVersion)
+	})
+
+	t.Run("newer versions are rejected", func(t *testing.T) {
+		newer := flux.NewCodecRegistry()
+		require.NoError(t, newer.Register("events", 2, flux.NewJSONCodec()))
+
+		codec, err := flux.NewEnvelopeCodec(newer, "events")
+		require.NoError(t, err)
+
+		data, err := codec.Encode(generator.generateRandomEvent())
+		require.NoError(t, err)
+
+		_, err = old.Decode(data)
+		require.ErrorIs(t, err, flux.ErrUnsupportedSchemaVersion)
+	})
+
+	t.Run("migrations must be from an older version", func(t *testing.T) {
+		registry := flux.NewCodecRegistry()
+		require.NoError(t, registry.Register("events", 2, flux.NewJSONCodec()))
+
+		require.Error(t, registry.RegisterMigration("events", 2, rename("v2", "", "")))
+		require.ErrorIs(t, registry.RegisterMigration("other", 1, rename("v1", "", "")), flux.ErrUnknownFormat)
+	})
+}
@@ -18,6 +18,10 @@ var ErrEventNotFound = errors.New("event not found")
 // ErrNoMoreEvents is an error that is returned when there are no event with a higher sequence number than provided.
 var ErrNoMoreEvents = errors.New("no more events")
 
+// ErrWaitNotSupported is an error that is returned by an EventWaiter which is not able to signal new events, such as
+// an event store which has not been configured to do so.
+var ErrWaitNotSupported = errors.New("waiting for events is not supported")
+
 type Event interface {
 	// ID returns a unique identifier for the event.
 	ID() string
@@ -34,17 +38,31 @@ type Event interface {
 
 	// Timestamp returns the time at which the event was emitted.
 	Timestamp() time.Time
+
+	// Payload returns the body of the event. It is nil if the event was created without a payload.
+	Payload() []byte
+
+	// ContentType describes the encoding of the payload, e.g. "application/json". It is empty if the event was created
+	// without a payload.
+	ContentType() string
+
+	// TraceParent returns the W3C traceparent of the span in which the event was created. It is empty if the event was
+	// not created within a trace.
+	TraceParent() string
 }
 
 // Compile-time assertion that defaultEvent implements the Event interface.
 var _ Event = (*defaultEvent)(nil)
 
 type defaultEvent struct {
-	id        string
-	topic     EventTopic
-	sequence  uint
-	key       string
-	timestamp time.Time
+	id          string
+	topic       EventTopic
+	sequence    uint
+	key         string
+	timestamp   time.Time
+	payload     []byte
+	contentType string
+	traceParent string
 }
 
 func (event *defaultEvent) ID() string           { return event.id }
@@ -52,6 +70,9 @@ func (event *defaultEvent) Topic() EventTopic    { return event.topic }
 func (event *defaultEvent) Sequence() uint       { return event.sequence }
 func (event *defaultEvent) Key() string          { return event.key }
 func (event *defaultEvent) Timestamp() time.Time { return event.timestamp }
+func (event *defaultEvent) Payload() []byte      { return event.payload }
+func (event *defaultEvent) ContentType() string  { return event.contentType }
+func (event *defaultEvent) TraceParent() string  { return event.traceParent }
 
 // EventStore is an interface that combines an EventReader and an EventWriter.
 type EventStore interface {
@@ -68,14 +89,76 @@ type EventReader interface {
 
 	// NextEvents returns the next batch of events in the event store.
 	//
-	// Must return ErrNoMoreEvents if there are no events in the event store.
+	// Must return ErrNoMoreEvents if there are no events with a sequence greater than from, which are older than the
+	// stream lag. Readers treat ErrEventNotFound the same way, as it is returned in its place by existing stores.
 	NextEvents(ctx context.Context, from, batchSize uint, streamLag time.Duration) ([]Event, error)
 }
 
+// EventWaiter is implemented by event stores which can signal that new events have been created, so that readers do
+// not need to poll for them.
+type EventWaiter interface {
+	// Wait blocks until there may be events with a sequence greater than from, which are older than the stream lag,
+	// or the context is cancelled. It returns ErrWaitNotSupported if the event store cannot signal new events, in
+	// which case readers poll for them.
+	Wait(ctx context.Context, from uint, streamLag time.Duration) error
+}
+
 // EventWriter allows write-only access to an event store.
 type EventWriter interface {
 	// CreateEvent creates a new event in the event store.
-	CreateEvent(ctx context.Context, topic, key string) (Event, error)
+	CreateEvent(ctx context.Context, topic, key string, opts ...EventOption) (Event, error)
+}
+
+// EventConfig holds the optional attributes of an event that is being created.
+type EventConfig struct {
+	// The body of the event.
+	Payload []byte
+
+	// The encoding of the payload, e.g. "application/json".
+	ContentType string
+
+	// The W3C traceparent of the span in which the event was created. If empty, it is taken from the context passed to
+	// CreateEvent.
+	TraceParent string
+}
+
+// NewEventConfig returns an EventConfig with the given options applied.
+func NewEventConfig(opts ...EventOption) *EventConfig {
+	var config EventConfig
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &config
+}
+
+// EventOption is an interface that allows for functional options to be applied to an EventConfig.
+type EventOption interface {
+	Apply(*EventConfig)
+}
+
+// EventOptionFunc is a function type that implements the EventOption interface.
+type EventOptionFunc func(*EventConfig)
+
+// Apply applies the function to the event config.
+func (f EventOptionFunc) Apply(config *EventConfig) {
+	f(config)
+}
+
+// WithPayload sets the payload of the event, along with the content type that describes its encoding.
+func WithPayload(contentType string, payload []byte) EventOption {
+	return EventOptionFunc(func(config *EventConfig) {
+		config.ContentType = contentType
+		config.Payload = payload
+	})
+}
+
+// WithTraceParent sets the W3C traceparent of the event, overriding the one taken from the context passed to
+// CreateEvent. This is useful when events are created on behalf of a request that was traced elsewhere.
+func WithTraceParent(traceParent string) EventOption {
+	return EventOptionFunc(func(config *EventConfig) {
+		config.TraceParent = traceParent
+	})
 }
 
 // EventFilter defines a type that determines whether an event should be included in the event stream.
@@ -112,3 +195,10 @@ func MatchTopics(topics ...EventTopic) EventFilterFunc {
 		return false
 	}
 }
+
+// MatchSince returns an EventFilter that filters out events which were emitted before the given time.
+func MatchSince(ts time.Time) EventFilterFunc {
+	return func(e Event) bool {
+		return !e.Timestamp().Before(ts)
+	}
+}
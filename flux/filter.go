@@ -0,0 +1,146 @@
+package flux
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// And returns an EventFilter that matches events which match all of the filters. It matches every event if there are
+// no filters.
+func And(filters ...EventFilter) EventFilterFunc {
+	return func(e Event) bool {
+		for _, filter := range filters {
+			if !filter.Apply(e) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Or returns an EventFilter that matches events which match any of the filters. It matches no events if there are no
+// filters.
+func Or(filters ...EventFilter) EventFilterFunc {
+	return func(e Event) bool {
+		for _, filter := range filters {
+			if filter.Apply(e) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Not returns an EventFilter that matches events which do not match the filter.
+func Not(filter EventFilter) EventFilterFunc {
+	return func(e Event) bool {
+		return !filter.Apply(e)
+	}
+}
+
+// MatchKeyPrefix returns an EventFilter that filters events by the prefix of their keys.
+func MatchKeyPrefix(prefix string) EventFilterFunc {
+	return func(e Event) bool {
+		return strings.HasPrefix(e.Key(), prefix)
+	}
+}
+
+// MatchKeyGlob returns an EventFilter that filters events by matching their keys against a glob pattern, in which *
+// matches any sequence of characters and ? matches a single character. Every other character matches itself.
+func MatchKeyGlob(pattern string) EventFilterFunc {
+	var expr strings.Builder
+
+	expr.WriteString(`(?s)^`)
+
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(`.*`)
+		case '?':
+			expr.WriteString(`.`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	expr.WriteString(`$`)
+
+	return MatchKeyRegexp(regexp.MustCompile(expr.String()))
+}
+
+// MatchKeyRegexp returns an EventFilter that filters events by matching their keys against a regular expression.
+func MatchKeyRegexp(re *regexp.Regexp) EventFilterFunc {
+	return func(e Event) bool {
+		return re.MatchString(e.Key())
+	}
+}
+
+// MatchTopicPatterns returns an EventFilter that filters events by matching their topics against any of the patterns.
+//
+// Topics are made up of tokens separated by dots. In a pattern, * matches any single token, and > matches one or more
+// tokens if it is the last token of the pattern. For example, "orders.*" matches "orders.created" but not
+// "orders.created.eu", which is matched by "orders.>".
+func MatchTopicPatterns(patterns ...string) EventFilterFunc {
+	return func(e Event) bool {
+		for _, pattern := range patterns {
+			if matchTopicPattern(pattern, e.Topic().String()) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// matchTopicPattern returns true if the topic matches the pattern, as described by MatchTopicPatterns.
+func matchTopicPattern(pattern, topic string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	topicTokens := strings.Split(topic, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" && i == len(patternTokens)-1 {
+			return len(topicTokens) > i
+		}
+
+		if i >= len(topicTokens) {
+			return false
+		}
+
+		if token != "*" && token != topicTokens[i] {
+			return false
+		}
+	}
+
+	return len(patternTokens) == len(topicTokens)
+}
+
+// MatchTimeWindow returns an EventFilter that filters out events which were emitted before since, or at or after
+// before. A zero time leaves the window unbounded on that side.
+func MatchTimeWindow(since, before time.Time) EventFilterFunc {
+	return func(e Event) bool {
+		if !since.IsZero() && e.Timestamp().Before(since) {
+			return false
+		}
+
+		if !before.IsZero() && !e.Timestamp().Before(before) {
+			return false
+		}
+
+		return true
+	}
+}
+
+// MatchSequenceRange returns an EventFilter that filters out events whose sequence is outside of the inclusive range
+// from first to last. The range has no upper bound if last is zero.
+func MatchSequenceRange(first, last uint) EventFilterFunc {
+	return func(e Event) bool {
+		if e.Sequence() < first {
+			return false
+		}
+
+		return last == 0 || e.Sequence() <= last
+	}
+}
@@ -0,0 +1,71 @@
+package flux_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventFilters(t *testing.T) {
+	now := time.Now()
+
+	event := func(topic, key string, sequence uint, timestamp time.Time) flux.Event {
+		return &testEvent{topic: flux.EventTopic(topic), key: key, sequence: sequence, timestamp: timestamp}
+	}
+
+	order := event("orders.created", "customer/1234", 10, now)
+
+	tests := []struct {
+		name   string
+		filter flux.EventFilter
+		want   bool
+	}{
+		{"and", flux.And(flux.MatchKey("customer/1234"), flux.MatchTopics("orders.created")), true},
+		{"and with a mismatch", flux.And(flux.MatchKey("customer/1234"), flux.MatchTopics("orders.deleted")), false},
+		{"empty and", flux.And(), true},
+		{"or", flux.Or(flux.MatchKey("unknown"), flux.MatchTopics("orders.created")), true},
+		{"or without a match", flux.Or(flux.MatchKey("unknown"), flux.MatchTopics("orders.deleted")), false},
+		{"empty or", flux.Or(), false},
+		{"not", flux.Not(flux.MatchKey("unknown")), true},
+		{"nested", flux.Not(flux.Or(flux.MatchKey("unknown"), flux.And(flux.MatchKeyPrefix("customer/")))), false},
+
+		{"key prefix", flux.MatchKeyPrefix("customer/"), true},
+		{"key prefix mismatch", flux.MatchKeyPrefix("supplier/"), false},
+		{"key glob", flux.MatchKeyGlob("customer/*"), true},
+		{"key glob single character", flux.MatchKeyGlob("customer/123?"), true},
+		{"key glob is anchored", flux.MatchKeyGlob("customer/12"), false},
+		{"key glob escapes metacharacters", flux.MatchKeyGlob("customer.1234"), false},
+		{"key regexp", flux.MatchKeyRegexp(regexp.MustCompile(`^customer/\d+$`)), true},
+		{"key regexp mismatch", flux.MatchKeyRegexp(regexp.MustCompile(`^supplier/`)), false},
+
+		{"topic pattern", flux.MatchTopicPatterns("orders.*"), true},
+		{"topic pattern literal", flux.MatchTopicPatterns("orders.created"), true},
+		{"topic pattern any", flux.MatchTopicPatterns("payments.*", "*.created"), true},
+		{"topic pattern too short", flux.MatchTopicPatterns("orders"), false},
+		{"topic pattern too long", flux.MatchTopicPatterns("orders.*.eu"), false},
+		{"topic pattern wildcard is a single token", flux.MatchTopicPatterns("*"), false},
+		{"topic pattern tail", flux.MatchTopicPatterns("orders.>"), true},
+		{"topic pattern tail needs a token", flux.MatchTopicPatterns("orders.created.>"), false},
+
+		{"time window", flux.MatchTimeWindow(now.Add(-time.Minute), now.Add(time.Minute)), true},
+		{"time window since is inclusive", flux.MatchTimeWindow(now, time.Time{}), true},
+		{"time window before is exclusive", flux.MatchTimeWindow(time.Time{}, now), false},
+		{"time window unbounded", flux.MatchTimeWindow(time.Time{}, time.Time{}), true},
+		{"time window in the past", flux.MatchTimeWindow(now.Add(-time.Hour), now.Add(-time.Minute)), false},
+
+		{"sequence range", flux.MatchSequenceRange(5, 15), true},
+		{"sequence range is inclusive", flux.MatchSequenceRange(10, 10), true},
+		{"sequence range unbounded", flux.MatchSequenceRange(10, 0), true},
+		{"sequence range below", flux.MatchSequenceRange(11, 0), false},
+		{"sequence range above", flux.MatchSequenceRange(1, 9), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.filter.Apply(order))
+		})
+	}
+}
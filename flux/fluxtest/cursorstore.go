@@ -0,0 +1,129 @@
+package fluxtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+// RunCursorStoreSuite runs a suite of tests which verify that a CursorStore behaves according to the contracts of the
+// CursorReader and CursorWriter interfaces.
+func RunCursorStoreSuite(t *testing.T, factory CursorStoreFactory) {
+	t.Helper()
+
+	t.Run("create cursor", func(t *testing.T) {
+		testCreateCursor(t, factory(t))
+	})
+
+	t.Run("lookup cursor", func(t *testing.T) {
+		testLookupCursor(t, factory(t))
+	})
+
+	t.Run("cursor not found", func(t *testing.T) {
+		testCursorNotFound(t, factory(t))
+	})
+
+	t.Run("update cursor", func(t *testing.T) {
+		testUpdateCursor(t, factory(t))
+	})
+
+	t.Run("independent cursors", func(t *testing.T) {
+		testIndependentCursors(t, factory(t))
+	})
+}
+
+func requireCursorEqual(t *testing.T, expected, actual flux.Cursor) {
+	t.Helper()
+
+	require.NotNil(t, actual)
+	require.Equal(t, expected.ID(), actual.ID())
+	require.Equal(t, expected.Name(), actual.Name())
+	require.Equal(t, expected.Sequence(), actual.Sequence())
+	require.True(t, expected.CreatedAt().Equal(actual.CreatedAt()))
+	require.True(t, expected.UpdatedAt().Equal(actual.UpdatedAt()))
+}
+
+func testCreateCursor(t *testing.T, store flux.CursorStore) {
+	name := uuid.NewString()
+
+	cursor, err := store.CreateCursor(context.Background(), name, 3)
+	require.NoError(t, err)
+	require.NotNil(t, cursor)
+
+	require.NotEmpty(t, cursor.ID())
+	require.Equal(t, name, cursor.Name())
+	require.Equal(t, uint(3), cursor.Sequence())
+	requireRecent(t, cursor.CreatedAt())
+	requireRecent(t, cursor.UpdatedAt())
+}
+
+func testLookupCursor(t *testing.T, store flux.CursorStore) {
+	cursor, err := store.CreateCursor(context.Background(), uuid.NewString(), 0)
+	require.NoError(t, err)
+
+	t.Run("by id", func(t *testing.T) {
+		found, err := store.LookupCursorByID(context.Background(), cursor.ID())
+		require.NoError(t, err)
+		requireCursorEqual(t, cursor, found)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		found, err := store.LookupCursorByName(context.Background(), cursor.Name())
+		require.NoError(t, err)
+		requireCursorEqual(t, cursor, found)
+	})
+}
+
+func testCursorNotFound(t *testing.T, store flux.CursorStore) {
+	_, err := store.CreateCursor(context.Background(), uuid.NewString(), 0)
+	require.NoError(t, err)
+
+	t.Run("by id", func(t *testing.T) {
+		cursor, err := store.LookupCursorByID(context.Background(), uuid.NewString())
+		require.ErrorIs(t, err, flux.ErrCursorNotFound)
+		require.Nil(t, cursor)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		cursor, err := store.LookupCursorByName(context.Background(), uuid.NewString())
+		require.ErrorIs(t, err, flux.ErrCursorNotFound)
+		require.Nil(t, cursor)
+	})
+}
+
+func testUpdateCursor(t *testing.T, store flux.CursorStore) {
+	cursor, err := store.CreateCursor(context.Background(), uuid.NewString(), 0)
+	require.NoError(t, err)
+
+	for _, sequence := range []uint{1, 5, 42} {
+		err := store.UpdateCursor(context.Background(), cursor.ID(), sequence)
+		require.NoError(t, err)
+
+		updated, err := store.LookupCursorByID(context.Background(), cursor.ID())
+		require.NoError(t, err)
+
+		require.Equal(t, sequence, updated.Sequence())
+		require.Equal(t, cursor.Name(), updated.Name())
+		require.True(t, cursor.CreatedAt().Equal(updated.CreatedAt()))
+		require.False(t, updated.UpdatedAt().Before(cursor.UpdatedAt()))
+	}
+}
+
+func testIndependentCursors(t *testing.T, store flux.CursorStore) {
+	a, err := store.CreateCursor(context.Background(), uuid.NewString(), 0)
+	require.NoError(t, err)
+
+	b, err := store.CreateCursor(context.Background(), uuid.NewString(), 0)
+	require.NoError(t, err)
+
+	require.NotEqual(t, a.ID(), b.ID())
+
+	require.NoError(t, store.UpdateCursor(context.Background(), a.ID(), 10))
+
+	found, err := store.LookupCursorByID(context.Background(), b.ID())
+	require.NoError(t, err)
+	require.Equal(t, uint(0), found.Sequence())
+}
@@ -0,0 +1,356 @@
+package fluxtest
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+// RunEventStoreSuite runs a suite of tests which verify that an EventStore behaves according to the contracts of the
+// EventReader and EventWriter interfaces.
+func RunEventStoreSuite(t *testing.T, factory EventStoreFactory) {
+	t.Helper()
+
+	t.Run("empty store", func(t *testing.T) {
+		testEmptyEventStore(t, factory(t))
+	})
+
+	t.Run("create event", func(t *testing.T) {
+		testCreateEvent(t, factory(t))
+	})
+
+	t.Run("head", func(t *testing.T) {
+		testHead(t, factory(t))
+	})
+
+	t.Run("ordering", func(t *testing.T) {
+		testOrdering(t, factory(t))
+	})
+
+	t.Run("batch sizes", func(t *testing.T) {
+		testBatchSizes(t, factory(t))
+	})
+
+	t.Run("no more events", func(t *testing.T) {
+		testNoMoreEvents(t, factory(t))
+	})
+
+	t.Run("stream lag", func(t *testing.T) {
+		testStreamLag(t, factory(t))
+	})
+
+	t.Run("concurrent writers", func(t *testing.T) {
+		testConcurrentWriters(t, factory(t))
+	})
+
+	t.Run("timestamp precision", func(t *testing.T) {
+		testTimestampPrecision(t, factory(t))
+	})
+}
+
+func createEvents(t *testing.T, store flux.EventWriter, count int) []flux.Event {
+	t.Helper()
+
+	events := make([]flux.Event, 0, count)
+	for i := 0; i < count; i++ {
+		event, err := store.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func testEmptyEventStore(t *testing.T, store flux.EventStore) {
+	event, err := store.Head(context.Background())
+	require.ErrorIs(t, err, flux.ErrEventNotFound)
+	require.Nil(t, event)
+
+	events, err := store.NextEvents(context.Background(), 0, 5, 0)
+	requireNoMoreEvents(t, err)
+	require.Empty(t, events)
+}
+
+func testCreateEvent(t *testing.T, store flux.EventStore) {
+	var (
+		topic   = uuid.NewString()
+		key     = uuid.NewString()
+		payload = []byte(`{"name":"test"}`)
+	)
+
+	t.Run("without payload", func(t *testing.T) {
+		event, err := store.CreateEvent(context.Background(), topic, key)
+		require.NoError(t, err)
+		require.NotNil(t, event)
+
+		require.NotEmpty(t, event.ID())
+		require.Equal(t, flux.EventTopic(topic), event.Topic())
+		require.Equal(t, key, event.Key())
+		require.NotZero(t, event.Sequence())
+		require.Empty(t, event.Payload())
+		require.Empty(t, event.ContentType())
+		require.Empty(t, event.TraceParent())
+		requireRecent(t, event.Timestamp())
+	})
+
+	t.Run("with payload", func(t *testing.T) {
+		event, err := store.CreateEvent(context.Background(), topic, key, flux.WithPayload("application/json", payload))
+		require.NoError(t, err)
+		require.NotNil(t, event)
+
+		require.Equal(t, payload, event.Payload())
+		require.Equal(t, "application/json", event.ContentType())
+
+		head, err := store.Head(context.Background())
+		require.NoError(t, err)
+		requireEventEqual(t, event, head)
+	})
+
+	t.Run("with trace parent", func(t *testing.T) {
+		const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		event, err := store.CreateEvent(context.Background(), topic, key, flux.WithTraceParent(traceParent))
+		require.NoError(t, err)
+		require.Equal(t, traceParent, event.TraceParent())
+
+		head, err := store.Head(context.Background())
+		require.NoError(t, err)
+		requireEventEqual(t, event, head)
+	})
+
+	t.Run("unique ids", func(t *testing.T) {
+		events := createEvents(t, store, 10)
+
+		ids := make(map[string]struct{})
+		for _, event := range events {
+			ids[event.ID()] = struct{}{}
+		}
+
+		require.Len(t, ids, len(events))
+	})
+}
+
+func testHead(t *testing.T, store flux.EventStore) {
+	for i := 0; i < 3; i++ {
+		event, err := store.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		head, err := store.Head(context.Background())
+		require.NoError(t, err)
+		requireEventEqual(t, event, head)
+	}
+}
+
+func testOrdering(t *testing.T, store flux.EventStore) {
+	created := createEvents(t, store, 10)
+
+	for i := 1; i < len(created); i++ {
+		require.Greater(t, created[i].Sequence(), created[i-1].Sequence(), "sequences must increase monotonically")
+	}
+
+	t.Run("events are returned in sequence order", func(t *testing.T) {
+		events, err := store.NextEvents(context.Background(), 0, uint(len(created)), 0)
+		require.NoError(t, err)
+		require.Len(t, events, len(created))
+
+		for i := range created {
+			requireEventEqual(t, created[i], events[i])
+		}
+	})
+
+	t.Run("events are returned after the given sequence", func(t *testing.T) {
+		from := created[4].Sequence()
+
+		events, err := store.NextEvents(context.Background(), from, uint(len(created)), 0)
+		require.NoError(t, err)
+		require.Len(t, events, len(created)-5)
+
+		for _, event := range events {
+			require.Greater(t, event.Sequence(), from)
+		}
+
+		requireEventEqual(t, created[5], events[0])
+	})
+
+	t.Run("paging visits every event once", func(t *testing.T) {
+		var (
+			from    uint
+			visited []flux.Event
+		)
+
+		for {
+			events, err := store.NextEvents(context.Background(), from, 3, 0)
+			if err != nil {
+				requireNoMoreEvents(t, err)
+				break
+			}
+
+			visited = append(visited, events...)
+			from = events[len(events)-1].Sequence()
+		}
+
+		require.Len(t, visited, len(created))
+
+		for i := range created {
+			requireEventEqual(t, created[i], visited[i])
+		}
+	})
+}
+
+func testBatchSizes(t *testing.T, store flux.EventStore) {
+	created := createEvents(t, store, 5)
+
+	tests := []struct {
+		name      string
+		batchSize uint
+		expected  int
+	}{
+		{name: "single event", batchSize: 1, expected: 1},
+		{name: "full batch", batchSize: 3, expected: 3},
+		{name: "exact batch", batchSize: 5, expected: 5},
+		{name: "partially filled batch", batchSize: 8, expected: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := store.NextEvents(context.Background(), 0, tt.batchSize, 0)
+			require.NoError(t, err)
+			require.Len(t, events, tt.expected)
+
+			for i, event := range events {
+				requireEventEqual(t, created[i], event)
+			}
+		})
+	}
+}
+
+func testNoMoreEvents(t *testing.T, store flux.EventStore) {
+	created := createEvents(t, store, 3)
+
+	events, err := store.NextEvents(context.Background(), created[len(created)-1].Sequence(), 5, 0)
+	requireNoMoreEvents(t, err)
+	require.Empty(t, events)
+}
+
+func testStreamLag(t *testing.T, store flux.EventStore) {
+	const lag = 500 * time.Millisecond
+
+	older := createEvents(t, store, 2)
+
+	time.Sleep(2 * lag)
+
+	newer := createEvents(t, store, 2)
+
+	t.Run("lagged events are excluded", func(t *testing.T) {
+		events, err := store.NextEvents(context.Background(), 0, 10, lag)
+		require.NoError(t, err)
+		require.Len(t, events, len(older))
+
+		for i := range older {
+			requireEventEqual(t, older[i], events[i])
+		}
+	})
+
+	t.Run("only lagged events remain", func(t *testing.T) {
+		events, err := store.NextEvents(context.Background(), older[len(older)-1].Sequence(), 10, lag)
+		requireNoMoreEvents(t, err)
+		require.Empty(t, events)
+	})
+
+	t.Run("without lag", func(t *testing.T) {
+		events, err := store.NextEvents(context.Background(), 0, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, events, len(older)+len(newer))
+	})
+}
+
+func testConcurrentWriters(t *testing.T, store flux.EventStore) {
+	const (
+		writers = 8
+		writes  = 25
+	)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created = make(map[string]flux.Event)
+	)
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < writes; j++ {
+				event, err := store.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+				if !assertNoError(t, err) {
+					return
+				}
+
+				mu.Lock()
+				created[event.ID()] = event
+				mu.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	require.Len(t, created, writers*writes)
+
+	events, err := store.NextEvents(context.Background(), 0, writers*writes, 0)
+	require.NoError(t, err)
+	require.Len(t, events, writers*writes)
+
+	sequences := make(map[uint]struct{})
+	for i, event := range events {
+		requireEventEqual(t, created[event.ID()], event)
+
+		if i > 0 {
+			require.Greater(t, event.Sequence(), events[i-1].Sequence())
+		}
+
+		sequences[event.Sequence()] = struct{}{}
+	}
+
+	require.Len(t, sequences, writers*writes, "sequences must be unique")
+}
+
+// assertNoError reports an error without stopping the test, which is not allowed from outside the test goroutine.
+func assertNoError(t *testing.T, err error) bool {
+	t.Helper()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+		return false
+	}
+
+	return true
+}
+
+func testTimestampPrecision(t *testing.T, store flux.EventStore) {
+	created := createEvents(t, store, 3)
+
+	for i := 1; i < len(created); i++ {
+		require.False(t, created[i].Timestamp().Before(created[i-1].Timestamp()), "timestamps must not go backwards")
+	}
+
+	// The timestamp returned when an event is created must survive a round trip through the store.
+	head, err := store.Head(context.Background())
+	require.NoError(t, err)
+	require.True(t, created[len(created)-1].Timestamp().Equal(head.Timestamp()))
+
+	events, err := store.NextEvents(context.Background(), 0, uint(len(created)), 0)
+	require.NoError(t, err)
+
+	for i := range created {
+		require.True(t, created[i].Timestamp().Equal(events[i].Timestamp()))
+		require.Equal(t, time.UTC, events[i].Timestamp().Location(), "timestamps must be in UTC")
+	}
+}
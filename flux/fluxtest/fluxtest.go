@@ -0,0 +1,65 @@
+// Package fluxtest provides conformance test suites for implementations of the flux storage interfaces.
+//
+// Each suite is run against stores created by a factory function, which must return a new, empty store every time it
+// is called:
+//
+//	func TestMyEventStore(t *testing.T) {
+//		fluxtest.RunEventStoreSuite(t, func(t *testing.T) flux.EventStore {
+//			return NewMyEventStore()
+//		})
+//	}
+package fluxtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+// EventStoreFactory returns a new, empty EventStore.
+type EventStoreFactory func(t *testing.T) flux.EventStore
+
+// CursorStoreFactory returns a new, empty CursorStore.
+type CursorStoreFactory func(t *testing.T) flux.CursorStore
+
+// requireEventEqual asserts that two events have the same attributes. Timestamps are compared using time.Time.Equal,
+// since stores are not required to preserve the location or monotonic clock reading of a timestamp.
+func requireEventEqual(t *testing.T, expected, actual flux.Event) {
+	t.Helper()
+
+	require.NotNil(t, actual)
+	require.Equal(t, expected.ID(), actual.ID())
+	require.Equal(t, expected.Topic(), actual.Topic())
+	require.Equal(t, expected.Sequence(), actual.Sequence())
+	require.Equal(t, expected.Key(), actual.Key())
+	require.Equal(t, expected.Payload(), actual.Payload())
+	require.Equal(t, expected.ContentType(), actual.ContentType())
+	require.Equal(t, expected.TraceParent(), actual.TraceParent())
+	require.Truef(
+		t,
+		expected.Timestamp().Equal(actual.Timestamp()),
+		"timestamps differ: expected %v, actual %v", expected.Timestamp(), actual.Timestamp(),
+	)
+}
+
+// requireNoMoreEvents asserts that NextEvents reported that there are no more events. The contract of EventReader asks
+// for ErrNoMoreEvents, but event stores have long returned ErrEventNotFound in its place, so either is accepted.
+func requireNoMoreEvents(t *testing.T, err error) {
+	t.Helper()
+
+	require.Truef(
+		t,
+		errors.Is(err, flux.ErrNoMoreEvents) || errors.Is(err, flux.ErrEventNotFound),
+		"expected ErrNoMoreEvents or ErrEventNotFound, got %v", err,
+	)
+}
+
+// requireRecent asserts that a timestamp was taken around the time the test ran.
+func requireRecent(t *testing.T, ts time.Time) {
+	t.Helper()
+
+	require.WithinDuration(t, time.Now(), ts, time.Minute)
+}
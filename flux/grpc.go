@@ -0,0 +1,236 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/nickcorin/toolkit/flux/fluxpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Compile-time assertion that GRPCServer implements the fluxpb.FluxServer interface.
+var _ fluxpb.FluxServer = (*GRPCServer)(nil)
+
+// NewGRPCServer returns a new gRPC server which streams events from the given event reader.
+//
+// The options are applied to the Relay that is started for every client. Since each client gets its own Relay, any
+// backoff strategy passed in must be safe to share between them. Partitioned relays may be used, in which case each
+// client receives the events of different keys out of order.
+func NewGRPCServer(events EventReader, opts ...RelayOption) *GRPCServer {
+	return &GRPCServer{
+		events: events,
+		opts:   opts,
+	}
+}
+
+// GRPCServer is an implementation of the fluxpb.FluxServer interface. It runs a Relay for each connected client, which
+// streams events to the client until it disconnects.
+type GRPCServer struct {
+	fluxpb.UnimplementedFluxServer
+
+	events EventReader
+	opts   []RelayOption
+}
+
+// Dispatch streams events to the client, starting after the requested sequence and filtered by the requested filters.
+// It blocks until the client disconnects, or the relay fails permanently.
+func (s *GRPCServer) Dispatch(pb *fluxpb.StreamRequest, conn fluxpb.Flux_DispatchServer) error {
+	req, err := StreamRequestFromProto(pb)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx := conn.Context()
+
+	relay := NewRelay(NewGRPCDispatcher(conn), s.events, s.opts...)
+
+	err = relay.Start(ctx, req)
+	if err == nil || ctx.Err() != nil {
+		// The client has disconnected, so there is nobody left to report an error to.
+		return nil
+	}
+
+	return status.Errorf(codes.Internal, "relay events: %v", err)
+}
+
+// StreamRequestFromProto converts a protobuf StreamRequest into a StreamRequest.
+func StreamRequestFromProto(pb *fluxpb.StreamRequest) (StreamRequest, error) {
+	req := StreamRequest{
+		StartSequence: uint(pb.GetStartSequence()),
+	}
+
+	for _, filter := range pb.GetFilters() {
+		f, err := EventFilterFromProto(filter)
+		if err != nil {
+			return StreamRequest{}, err
+		}
+
+		req.Filters = append(req.Filters, f)
+	}
+
+	return req, nil
+}
+
+// EventFilterFromProto converts a protobuf EventFilter into an EventFilter.
+func EventFilterFromProto(pb *fluxpb.EventFilter) (EventFilter, error) {
+	switch f := pb.GetFilter().(type) {
+	case *fluxpb.EventFilter_Topics:
+		topics := splitList(f.Topics)
+		if len(topics) == 0 {
+			return nil, errors.New("topics filter must contain at least one topic")
+		}
+
+		eventTopics := make([]EventTopic, len(topics))
+		for i, topic := range topics {
+			eventTopics[i] = EventTopic(topic)
+		}
+
+		return MatchTopics(eventTopics...), nil
+	case *fluxpb.EventFilter_Key:
+		return MatchKey(f.Key), nil
+	case *fluxpb.EventFilter_Timestamp:
+		if err := f.Timestamp.CheckValid(); err != nil {
+			return nil, fmt.Errorf("invalid timestamp filter: %w", err)
+		}
+
+		return MatchSince(f.Timestamp.AsTime()), nil
+	case *fluxpb.EventFilter_KeyPrefix:
+		return MatchKeyPrefix(f.KeyPrefix), nil
+	case *fluxpb.EventFilter_KeyGlob:
+		return MatchKeyGlob(f.KeyGlob), nil
+	case *fluxpb.EventFilter_KeyRegex:
+		re, err := regexp.Compile(f.KeyRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key regex filter: %w", err)
+		}
+
+		return MatchKeyRegexp(re), nil
+	case *fluxpb.EventFilter_TopicPatterns:
+		patterns := splitList(f.TopicPatterns)
+		if len(patterns) == 0 {
+			return nil, errors.New("topic patterns filter must contain at least one pattern")
+		}
+
+		return MatchTopicPatterns(patterns...), nil
+	case *fluxpb.EventFilter_TimeWindow:
+		var since, before time.Time
+
+		if f.TimeWindow.GetSince() != nil {
+			if err := f.TimeWindow.GetSince().CheckValid(); err != nil {
+				return nil, fmt.Errorf("invalid time window filter: %w", err)
+			}
+
+			since = f.TimeWindow.GetSince().AsTime()
+		}
+
+		if f.TimeWindow.GetBefore() != nil {
+			if err := f.TimeWindow.GetBefore().CheckValid(); err != nil {
+				return nil, fmt.Errorf("invalid time window filter: %w", err)
+			}
+
+			before = f.TimeWindow.GetBefore().AsTime()
+		}
+
+		return MatchTimeWindow(since, before), nil
+	case *fluxpb.EventFilter_SequenceRange:
+		return MatchSequenceRange(uint(f.SequenceRange.GetMin()), uint(f.SequenceRange.GetMax())), nil
+	case *fluxpb.EventFilter_And:
+		filters, err := eventFiltersFromProto("and", f.And.GetFilters())
+		if err != nil {
+			return nil, err
+		}
+
+		return And(filters...), nil
+	case *fluxpb.EventFilter_Or:
+		filters, err := eventFiltersFromProto("or", f.Or.GetFilters())
+		if err != nil {
+			return nil, err
+		}
+
+		return Or(filters...), nil
+	case *fluxpb.EventFilter_Not:
+		filter, err := EventFilterFromProto(f.Not)
+		if err != nil {
+			return nil, fmt.Errorf("invalid not filter: %w", err)
+		}
+
+		return Not(filter), nil
+	default:
+		return nil, fmt.Errorf("unsupported filter type %T", f)
+	}
+}
+
+// eventFiltersFromProto converts the operands of a combined filter, which must have at least one operand.
+func eventFiltersFromProto(op string, pbs []*fluxpb.EventFilter) ([]EventFilter, error) {
+	if len(pbs) == 0 {
+		return nil, fmt.Errorf("%s filter must contain at least one filter", op)
+	}
+
+	filters := make([]EventFilter, len(pbs))
+	for i, pb := range pbs {
+		filter, err := EventFilterFromProto(pb)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s filter: %w", op, err)
+		}
+
+		filters[i] = filter
+	}
+
+	return filters, nil
+}
+
+// splitList splits a comma-separated list, dropping empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// NewGRPCClient returns a new client for a flux gRPC server.
+func NewGRPCClient(conn grpc.ClientConnInterface) *GRPCClient {
+	return &GRPCClient{
+		client: fluxpb.NewFluxClient(conn),
+	}
+}
+
+// GRPCClient is a client that receives events from a flux gRPC server.
+type GRPCClient struct {
+	client fluxpb.FluxClient
+}
+
+// Stream opens a new event stream. Events are received from the stream until the context is cancelled, or the server
+// ends the stream.
+func (c *GRPCClient) Stream(ctx context.Context, req *fluxpb.StreamRequest) (*GRPCStream, error) {
+	conn, err := c.client.Dispatch(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("open stream: %w", err)
+	}
+
+	return &GRPCStream{conn: conn}, nil
+}
+
+// GRPCStream is a stream of events received from a flux gRPC server.
+type GRPCStream struct {
+	conn fluxpb.Flux_DispatchClient
+}
+
+// Recv blocks until the next event is received. It returns io.EOF once the server has ended the stream.
+func (s *GRPCStream) Recv() (Event, error) {
+	pb, err := s.conn.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	return EventFromProto(pb), nil
+}
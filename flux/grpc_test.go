@@ -0,0 +1,286 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/nickcorin/toolkit/flux/fluxpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func setupGRPCClient(t *testing.T, events flux.EventReader, opts ...flux.RelayOption) *flux.GRPCClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+
+	server := grpc.NewServer()
+	opts = append([]flux.RelayOption{flux.WithBackOff(backoff.NewConstantBackOff(10 * time.Millisecond))}, opts...)
+	fluxpb.RegisterFluxServer(server, flux.NewGRPCServer(events, opts...))
+
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+
+	return flux.NewGRPCClient(conn)
+}
+
+func TestGRPCServer(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	events := make([]flux.Event, 0)
+	for i := 0; i < 10; i++ {
+		events = append(events, generator.generateEvent("test-topic", "test-key"))
+	}
+
+	other := generator.generateEvent("other-topic", "other-key")
+	events = append(events, other)
+
+	client := setupGRPCClient(t, &staticEventReader{events: events})
+
+	t.Run("stream all events", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.Stream(ctx, &fluxpb.StreamRequest{})
+		require.NoError(t, err)
+
+		for _, expected := range events {
+			e, err := stream.Recv()
+			require.NoError(t, err)
+			require.Equal(t, expected.ID(), e.ID())
+			require.Equal(t, expected.Sequence(), e.Sequence())
+		}
+	})
+
+	t.Run("stream from a start sequence", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.Stream(ctx, &fluxpb.StreamRequest{StartSequence: 5})
+		require.NoError(t, err)
+
+		e, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, events[5].ID(), e.ID())
+	})
+
+	t.Run("stream with filters", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			filter *fluxpb.EventFilter
+		}{
+			{
+				name:   "topics",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_Topics{Topics: "unknown, other-topic"}},
+			},
+			{
+				name:   "key",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_Key{Key: "other-key"}},
+			},
+			{
+				name: "timestamp",
+				filter: &fluxpb.EventFilter{
+					Filter: &fluxpb.EventFilter_Timestamp{Timestamp: timestamppb.New(other.Timestamp())},
+				},
+			},
+			{
+				name:   "key prefix",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_KeyPrefix{KeyPrefix: "other-"}},
+			},
+			{
+				name:   "key glob",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_KeyGlob{KeyGlob: "oth?r-*"}},
+			},
+			{
+				name:   "key regex",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_KeyRegex{KeyRegex: "^other-k.y$"}},
+			},
+			{
+				name: "topic patterns",
+				filter: &fluxpb.EventFilter{
+					Filter: &fluxpb.EventFilter_TopicPatterns{TopicPatterns: "test.>, other-topic"},
+				},
+			},
+			{
+				name: "time window",
+				filter: &fluxpb.EventFilter{
+					Filter: &fluxpb.EventFilter_TimeWindow{
+						TimeWindow: &fluxpb.TimeWindow{Since: timestamppb.New(other.Timestamp())},
+					},
+				},
+			},
+			{
+				name: "sequence range",
+				filter: &fluxpb.EventFilter{
+					Filter: &fluxpb.EventFilter_SequenceRange{
+						SequenceRange: &fluxpb.SequenceRange{Min: uint64(other.Sequence())},
+					},
+				},
+			},
+			{
+				name: "and",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_And{And: &fluxpb.EventFilters{
+					Filters: []*fluxpb.EventFilter{
+						{Filter: &fluxpb.EventFilter_Topics{Topics: "other-topic"}},
+						{Filter: &fluxpb.EventFilter_Key{Key: "other-key"}},
+					},
+				}}},
+			},
+			{
+				name: "or",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_Or{Or: &fluxpb.EventFilters{
+					Filters: []*fluxpb.EventFilter{
+						{Filter: &fluxpb.EventFilter_Key{Key: "unknown"}},
+						{Filter: &fluxpb.EventFilter_Key{Key: "other-key"}},
+					},
+				}}},
+			},
+			{
+				name: "not",
+				filter: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_Not{
+					Not: &fluxpb.EventFilter{Filter: &fluxpb.EventFilter_Key{Key: "test-key"}},
+				}},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				stream, err := client.Stream(ctx, &fluxpb.StreamRequest{Filters: []*fluxpb.EventFilter{tt.filter}})
+				require.NoError(t, err)
+
+				e, err := stream.Recv()
+				require.NoError(t, err)
+				require.Equal(t, other.ID(), e.ID())
+			})
+		}
+	})
+
+	t.Run("invalid filters", func(t *testing.T) {
+		tests := map[string]*fluxpb.EventFilter{
+			"empty topics":   {Filter: &fluxpb.EventFilter_Topics{Topics: ""}},
+			"empty patterns": {Filter: &fluxpb.EventFilter_TopicPatterns{TopicPatterns: " , "}},
+			"key regex":      {Filter: &fluxpb.EventFilter_KeyRegex{KeyRegex: "("}},
+			"empty and":      {Filter: &fluxpb.EventFilter_And{And: &fluxpb.EventFilters{}}},
+			"empty or":       {Filter: &fluxpb.EventFilter_Or{Or: &fluxpb.EventFilters{}}},
+			"missing not":    {Filter: &fluxpb.EventFilter_Not{}},
+			"missing filter": {},
+			"nested in and": {
+				Filter: &fluxpb.EventFilter_And{And: &fluxpb.EventFilters{Filters: []*fluxpb.EventFilter{{}}}},
+			},
+		}
+
+		for name, filter := range tests {
+			t.Run(name, func(t *testing.T) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				stream, err := client.Stream(ctx, &fluxpb.StreamRequest{Filters: []*fluxpb.EventFilter{filter}})
+				require.NoError(t, err)
+
+				_, err = stream.Recv()
+				require.Equal(t, codes.InvalidArgument, status.Code(err))
+			})
+		}
+	})
+}
+
+func TestGRPCServer_Partitions(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	events := make([]flux.Event, 0)
+	for i := 0; i < 100; i++ {
+		events = append(events, generator.generateEvent("test-topic", fmt.Sprintf("key-%d", i%10)))
+	}
+
+	client := setupGRPCClient(t, &staticEventReader{events: events}, flux.WithPartitions(4))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := client.Stream(ctx, &fluxpb.StreamRequest{})
+	require.NoError(t, err)
+
+	// Events of different keys may arrive out of order, but each is received intact and exactly once.
+	received := make(map[string]bool)
+	for range events {
+		e, err := stream.Recv()
+		require.NoError(t, err)
+		require.False(t, received[e.ID()])
+
+		received[e.ID()] = true
+	}
+
+	for _, e := range events {
+		require.True(t, received[e.ID()])
+	}
+}
+
+// serialSendStream is a server stream which fails if messages are sent on it concurrently.
+type serialSendStream struct {
+	grpc.ServerStream
+
+	sending atomic.Bool
+	sent    atomic.Int32
+}
+
+func (s *serialSendStream) Send(*fluxpb.Event) error {
+	if !s.sending.CompareAndSwap(false, true) {
+		return errors.New("concurrent send")
+	}
+	defer s.sending.Store(false)
+
+	time.Sleep(time.Millisecond)
+	s.sent.Add(1)
+
+	return nil
+}
+
+func TestGRPCDispatcher_ConcurrentDispatch(t *testing.T) {
+	generator := NewEventGenerator(t)
+
+	stream := &serialSendStream{}
+	dispatcher := flux.NewGRPCDispatcher(stream)
+
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		go func(e flux.Event) {
+			errs <- dispatcher.Dispatch(context.Background(), e)
+		}(generator.generateRandomEvent())
+	}
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	require.EqualValues(t, 10, stream.sent.Load())
+}
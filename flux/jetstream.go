@@ -0,0 +1,149 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Compile-time assertion that JetStreamDispatcher implements the Dispatcher interface.
+var _ Dispatcher = (*JetStreamDispatcher)(nil)
+
+// DefaultJetStreamSubjectTemplate publishes events to a subject with the same name as their topic.
+const DefaultJetStreamSubjectTemplate = "{{ .Topic }}"
+
+// NewJetStreamDispatcher returns a new NATS JetStream dispatcher.
+//
+// The subject and stream templates are text/templates which are executed with the event being dispatched, e.g.
+// "events.{{ .Topic }}". An error is returned if either template cannot be parsed.
+func NewJetStreamDispatcher(
+	js jetstream.JetStream,
+	codec Codec,
+	opts ...JetStreamDispatcherOption,
+) (*JetStreamDispatcher, error) {
+	config := JetStreamDispatcherConfig{
+		SubjectTemplate: DefaultJetStreamSubjectTemplate,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	subject, err := template.New("subject").Parse(config.SubjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("parse subject template: %w", err)
+	}
+
+	d := JetStreamDispatcher{
+		codec:   codec,
+		js:      js,
+		subject: subject,
+	}
+
+	if config.StreamTemplate != "" {
+		d.stream, err = template.New("stream").Parse(config.StreamTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parse stream template: %w", err)
+		}
+	}
+
+	return &d, nil
+}
+
+// JetStreamDispatcher is a dispatcher that publishes events to a NATS JetStream stream.
+//
+// Unlike the NatsDispatcher, it waits for the server to acknowledge that each event has been stored. Every message
+// carries the event's ID in its Nats-Msg-Id header, so events which are dispatched again after a relay retries are
+// discarded by the server, as long as they fall within the stream's duplicate window.
+type JetStreamDispatcher struct {
+	codec   Codec
+	js      jetstream.JetStream
+	subject *template.Template
+	stream  *template.Template
+}
+
+type JetStreamDispatcherConfig struct {
+	// A text/template which determines the subject that an event is published to.
+	SubjectTemplate string
+
+	// If set, a text/template which determines the name of the stream that an event is expected to be stored in.
+	// Publishing fails if the event's subject is bound to a different stream.
+	StreamTemplate string
+}
+
+// JetStreamDispatcherOption is an interface that allows for functional options to be applied to a
+// JetStreamDispatcherConfig.
+type JetStreamDispatcherOption interface {
+	Apply(*JetStreamDispatcherConfig)
+}
+
+// JetStreamDispatcherOptionFunc is a function type that implements the JetStreamDispatcherOption interface.
+type JetStreamDispatcherOptionFunc func(*JetStreamDispatcherConfig)
+
+// Apply applies the function to the dispatcher.
+func (f JetStreamDispatcherOptionFunc) Apply(config *JetStreamDispatcherConfig) {
+	f(config)
+}
+
+// WithSubjectTemplate sets the template used to determine the subject that an event is published to.
+func WithSubjectTemplate(tmpl string) JetStreamDispatcherOption {
+	return JetStreamDispatcherOptionFunc(func(config *JetStreamDispatcherConfig) {
+		config.SubjectTemplate = tmpl
+	})
+}
+
+// WithStreamTemplate sets the template used to determine the stream that an event is expected to be stored in.
+func WithStreamTemplate(tmpl string) JetStreamDispatcherOption {
+	return JetStreamDispatcherOptionFunc(func(config *JetStreamDispatcherConfig) {
+		config.StreamTemplate = tmpl
+	})
+}
+
+// Dispatch publishes the event, and blocks until it has been acknowledged by the server.
+func (d *JetStreamDispatcher) Dispatch(ctx context.Context, e Event) error {
+	subject, err := executeTemplate(d.subject, e)
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(subject)
+
+	data, err := encodeMessage(d.codec, e, msg.Header)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	msg.Data = data
+	msg.Header.Set(jetstream.MsgIDHeader, e.ID())
+	injectDeadLetter(e, msg.Header.Set)
+	injectTraceContext(ctx, msg.Header.Set)
+
+	var opts []jetstream.PublishOpt
+	if d.stream != nil {
+		stream, err := executeTemplate(d.stream, e)
+		if err != nil {
+			return err
+		}
+
+		opts = append(opts, jetstream.WithExpectStream(stream))
+	}
+
+	if _, err := d.js.PublishMsg(ctx, msg, opts...); err != nil {
+		return fmt.Errorf("failed to dispatch event: %w", err)
+	}
+
+	return nil
+}
+
+func executeTemplate(tmpl *template.Template, e Event) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, e); err != nil {
+		return "", fmt.Errorf("execute %s template: %w", tmpl.Name(), err)
+	}
+
+	return sb.String(), nil
+}
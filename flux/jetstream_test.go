@@ -0,0 +1,126 @@
+package flux_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+func setupNats(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+
+	require.True(t, srv.ReadyForConnections(5*time.Second), "nats server did not start")
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+func setupJetStream(t *testing.T) jetstream.JetStream {
+	t.Helper()
+
+	js, err := jetstream.New(setupNats(t))
+	require.NoError(t, err)
+
+	_, err = js.CreateStream(context.Background(), jetstream.StreamConfig{
+		Name:     "EVENTS",
+		Subjects: []string{"events.>"},
+	})
+	require.NoError(t, err)
+
+	return js
+}
+
+func TestJetStreamDispatcher(t *testing.T) {
+	js := setupJetStream(t)
+	codec := flux.NewJSONCodec()
+	generator := NewEventGenerator(t)
+
+	dispatcher, err := flux.NewJetStreamDispatcher(
+		js,
+		codec,
+		flux.WithSubjectTemplate("events.{{ .Topic }}"),
+		flux.WithStreamTemplate("EVENTS"),
+	)
+	require.NoError(t, err)
+
+	stream, err := js.Stream(context.Background(), "EVENTS")
+	require.NoError(t, err)
+
+	t.Run("publishes to the templated subject", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+		require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+
+		msg, err := stream.GetLastMsgForSubject(context.Background(), "events.test-topic")
+		require.NoError(t, err)
+		require.Equal(t, e.ID(), msg.Header.Get(jetstream.MsgIDHeader))
+
+		decoded, err := codec.Decode(msg.Data)
+		require.NoError(t, err)
+		require.Equal(t, e.ID(), decoded.ID())
+	})
+
+	t.Run("deduplicates retried events", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+
+		before, err := stream.Info(context.Background())
+		require.NoError(t, err)
+
+		require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+		require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+
+		after, err := stream.Info(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, before.State.Msgs+1, after.State.Msgs)
+	})
+
+	t.Run("fails without a stream for the subject", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		dispatcher, err := flux.NewJetStreamDispatcher(js, codec)
+		require.NoError(t, err)
+
+		err = dispatcher.Dispatch(ctx, generator.generateEvent("unknown", "test-key"))
+		require.Error(t, err)
+	})
+
+	t.Run("fails when the stream does not match", func(t *testing.T) {
+		dispatcher, err := flux.NewJetStreamDispatcher(
+			js,
+			codec,
+			flux.WithSubjectTemplate("events.{{ .Topic }}"),
+			flux.WithStreamTemplate("OTHER"),
+		)
+		require.NoError(t, err)
+
+		err = dispatcher.Dispatch(context.Background(), generator.generateEvent("test-topic", "test-key"))
+		require.Error(t, err)
+	})
+
+	t.Run("invalid template", func(t *testing.T) {
+		_, err := flux.NewJetStreamDispatcher(js, codec, flux.WithSubjectTemplate("{{ .Topic"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,113 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Headers which are set on every message produced by a KafkaDispatcher.
+const (
+	KafkaHeaderID        = "flux-id"
+	KafkaHeaderSequence  = "flux-sequence"
+	KafkaHeaderTimestamp = "flux-timestamp"
+)
+
+// Compile-time assertion that KafkaDispatcher implements the Dispatcher interface.
+var _ Dispatcher = (*KafkaDispatcher)(nil)
+
+// NewKafkaDispatcher returns a new Kafka dispatcher.
+//
+// Events are produced to a Kafka topic with the same name as the event's topic, unless WithKafkaTopicFunc is used.
+func NewKafkaDispatcher(client *kgo.Client, codec Codec, opts ...KafkaDispatcherOption) *KafkaDispatcher {
+	config := KafkaDispatcherConfig{
+		TopicFunc: func(e Event) string {
+			return e.Topic().String()
+		},
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &KafkaDispatcher{
+		client: client,
+		codec:  codec,
+		config: config,
+	}
+}
+
+// KafkaDispatcher is a dispatcher that produces events to a Kafka topic.
+//
+// Each event's key is used as the message key, so that the client's partitioner sends all events for the same entity
+// to the same partition, preserving their order. The event's ID, sequence and timestamp are sent as message headers.
+type KafkaDispatcher struct {
+	client *kgo.Client
+	codec  Codec
+	config KafkaDispatcherConfig
+}
+
+type KafkaDispatcherConfig struct {
+	// Returns the Kafka topic that an event should be produced to.
+	TopicFunc func(e Event) string
+}
+
+// KafkaDispatcherOption is an interface that allows for functional options to be applied to a KafkaDispatcherConfig.
+type KafkaDispatcherOption interface {
+	Apply(*KafkaDispatcherConfig)
+}
+
+// KafkaDispatcherOptionFunc is a function type that implements the KafkaDispatcherOption interface.
+type KafkaDispatcherOptionFunc func(*KafkaDispatcherConfig)
+
+// Apply applies the function to the dispatcher.
+func (f KafkaDispatcherOptionFunc) Apply(config *KafkaDispatcherConfig) {
+	f(config)
+}
+
+// WithKafkaTopicFunc sets the function used to determine the Kafka topic that an event is produced to.
+func WithKafkaTopicFunc(fn func(e Event) string) KafkaDispatcherOption {
+	return KafkaDispatcherOptionFunc(func(config *KafkaDispatcherConfig) {
+		config.TopicFunc = fn
+	})
+}
+
+// Dispatch produces the event, and blocks until it has been acknowledged by the broker. The details of a *DeadLetter
+// are sent in the headers named by the DeadLetterHeader constants, in lowercase like the other headers.
+func (d *KafkaDispatcher) Dispatch(ctx context.Context, e Event) error {
+	value, err := d.codec.Encode(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	record := &kgo.Record{
+		Topic: d.config.TopicFunc(e),
+		Key:   []byte(e.Key()),
+		Value: value,
+		Headers: []kgo.RecordHeader{
+			{Key: KafkaHeaderID, Value: []byte(e.ID())},
+			{Key: KafkaHeaderSequence, Value: []byte(strconv.FormatUint(uint64(e.Sequence()), 10))},
+			{Key: KafkaHeaderTimestamp, Value: []byte(e.Timestamp().Format(time.RFC3339Nano))},
+		},
+		Timestamp: e.Timestamp(),
+	}
+
+	// Kafka headers are case-sensitive, and the headers set by the dispatcher are lowercase.
+	injectDeadLetter(e, func(key, value string) {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: strings.ToLower(key), Value: []byte(value)})
+	})
+
+	injectTraceContext(ctx, func(key, value string) {
+		record.Headers = append(record.Headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+	})
+
+	if err := d.client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return fmt.Errorf("failed to dispatch event: %w", err)
+	}
+
+	return nil
+}
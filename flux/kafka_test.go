@@ -0,0 +1,179 @@
+package flux_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func setupKafka(t *testing.T, partitions int32, topics ...string) (*kgo.Client, *kgo.Client) {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(partitions, topics...))
+	require.NoError(t, err)
+	t.Cleanup(cluster.Close)
+
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	require.NoError(t, err)
+	t.Cleanup(producer.Close)
+
+	consumer, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics(topics...),
+		kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(consumer.Close)
+
+	return producer, consumer
+}
+
+func pollRecords(t *testing.T, client *kgo.Client, count int) []*kgo.Record {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var records []*kgo.Record
+	for len(records) < count {
+		fetches := client.PollFetches(ctx)
+		require.NoError(t, ctx.Err())
+
+		fetches.EachError(func(topic string, partition int32, err error) {
+			t.Fatalf("fetch %s/%d: %v", topic, partition, err)
+		})
+
+		records = append(records, fetches.Records()...)
+	}
+
+	return records
+}
+
+func TestKafkaDispatcher(t *testing.T) {
+	const topic = "test-topic"
+
+	producer, consumer := setupKafka(t, 4, topic)
+
+	codec := flux.NewJSONCodec()
+	dispatcher := flux.NewKafkaDispatcher(producer, codec)
+
+	generator := NewEventGenerator(t)
+
+	keys := []string{"key-a", "key-b", "key-c"}
+
+	var events []flux.Event
+	for i := 0; i < 12; i++ {
+		e := generator.generateEvent(topic, keys[i%len(keys)])
+		require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+
+		events = append(events, e)
+	}
+
+	records := pollRecords(t, consumer, len(events))
+	require.Len(t, records, len(events))
+
+	byID := make(map[string]flux.Event)
+	for _, e := range events {
+		byID[e.ID()] = e
+	}
+
+	partitions := make(map[string]int32)
+	lastSequence := make(map[string]uint)
+
+	for _, record := range records {
+		headers := make(map[string]string)
+		for _, header := range record.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+
+		expected, ok := byID[headers[flux.KafkaHeaderID]]
+		require.True(t, ok)
+
+		require.Equal(t, topic, record.Topic)
+		require.Equal(t, expected.Key(), string(record.Key))
+		require.Equal(t, strconv.FormatUint(uint64(expected.Sequence()), 10), headers[flux.KafkaHeaderSequence])
+		require.Equal(t, expected.Timestamp().Format(time.RFC3339Nano), headers[flux.KafkaHeaderTimestamp])
+
+		decoded, err := codec.Decode(record.Value)
+		require.NoError(t, err)
+		require.Equal(t, expected.ID(), decoded.ID())
+
+		// Events with the same key must land on the same partition, in order.
+		if partition, ok := partitions[expected.Key()]; ok {
+			require.Equal(t, partition, record.Partition)
+		}
+		partitions[expected.Key()] = record.Partition
+
+		require.Greater(t, expected.Sequence(), lastSequence[expected.Key()])
+		lastSequence[expected.Key()] = expected.Sequence()
+	}
+}
+
+func TestKafkaDispatcher_TopicFunc(t *testing.T) {
+	producer, consumer := setupKafka(t, 1, "events.test-topic")
+
+	dispatcher := flux.NewKafkaDispatcher(
+		producer,
+		flux.NewProtobufCodec(),
+		flux.WithKafkaTopicFunc(func(e flux.Event) string {
+			return "events." + e.Topic().String()
+		}),
+	)
+
+	e := NewEventGenerator(t).generateEvent("test-topic", "test-key")
+	require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+
+	records := pollRecords(t, consumer, 1)
+	require.Equal(t, "events.test-topic", records[0].Topic)
+}
+
+func TestKafkaDispatcher_UnknownTopic(t *testing.T) {
+	producer, _ := setupKafka(t, 1, "test-topic")
+
+	dispatcher := flux.NewKafkaDispatcher(producer, flux.NewJSONCodec())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	e := NewEventGenerator(t).generateEvent("unknown-topic", "test-key")
+	require.Error(t, dispatcher.Dispatch(ctx, e))
+}
+
+func TestKafkaDispatcher_DeadLetter(t *testing.T) {
+	const topic = "test-topic"
+
+	producer, consumer := setupKafka(t, 1, topic)
+
+	letter := &flux.DeadLetter{
+		Event:          NewEventGenerator(t).generateEvent(topic, "test-key"),
+		Cause:          "handler failed:\nconnection refused",
+		Attempts:       3,
+		DeadLetteredAt: time.Now().UTC(),
+	}
+
+	dispatcher := flux.NewKafkaDispatcher(producer, flux.NewJSONCodec())
+	require.NoError(t, flux.DispatchDeadLetters(dispatcher).DeadLetter(context.Background(), letter))
+
+	records := pollRecords(t, consumer, 1)
+
+	headers := make(map[string]string)
+	for _, header := range records[0].Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	// Control characters are replaced, as they are not allowed in the headers of other transports.
+	require.Equal(t, "handler failed: connection refused", headers[strings.ToLower(flux.DeadLetterHeaderCause)])
+	require.Equal(t, "3", headers[strings.ToLower(flux.DeadLetterHeaderAttempts)])
+	require.Equal(
+		t,
+		letter.DeadLetteredAt.Format(time.RFC3339Nano),
+		headers[strings.ToLower(flux.DeadLetterHeaderTime)],
+	)
+}
@@ -0,0 +1,144 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultLeaseTTL is the lease TTL used by relays which do not configure one.
+const DefaultLeaseTTL = 15 * time.Second
+
+// MinLeaseTTL is the shortest lease TTL a relay accepts. The lease is renewed every third of the TTL, so shorter TTLs
+// leave too little time for a renewal to complete.
+const MinLeaseTTL = 30 * time.Millisecond
+
+// ErrInvalidLeaseTTL is an error that is returned when a relay is started with a lease TTL shorter than MinLeaseTTL.
+var ErrInvalidLeaseTTL = errors.New("invalid lease ttl")
+
+// LeaseStore grants named, time-limited leases, which allow one of several processes to act as a leader.
+type LeaseStore interface {
+	// AcquireLease acquires the named lease for the holder, or renews it if the holder already holds it. It returns
+	// false if the lease is held by another holder which has not let it expire.
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+
+	// ReleaseLease releases the named lease, if it is held by the holder, so that it may be acquired immediately.
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// lead campaigns for the relay's lease, running the relay whenever it is the leader, until it is shut down or fails.
+func (r *Relay) lead(ctx context.Context, run func(context.Context) error) error {
+	holder := uuid.NewString()
+
+	ttl := r.config.LeaseTTL
+	if ttl == 0 {
+		ttl = DefaultLeaseTTL
+	}
+
+	if ttl < MinLeaseTTL {
+		return fmt.Errorf("%w: %s is shorter than %s", ErrInvalidLeaseTTL, ttl, MinLeaseTTL)
+	}
+
+	for {
+		select {
+		case <-r.shutdown:
+			return nil
+		default:
+		}
+
+		acquired, err := r.config.Leases.AcquireLease(ctx, r.config.LeaseName, holder, ttl)
+		if err != nil {
+			r.observer.LeaseError(ctx, r.config.LeaseName, fmt.Errorf("failed to acquire lease: %w", err))
+		}
+
+		if acquired {
+			r.observer.LeaseAcquired(ctx, r.config.LeaseName)
+
+			lost, err := r.term(ctx, holder, ttl, run)
+			if !lost {
+				return err
+			}
+
+			r.observer.LeaseLost(ctx, r.config.LeaseName, err)
+		}
+
+		select {
+		case <-r.shutdown:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(ttl / 3):
+		}
+	}
+}
+
+// errLeaseTaken is the reason a lease is lost when another holder has acquired it.
+var errLeaseTaken = errors.New("lease is held by another holder")
+
+// term runs the relay while renewing its lease. It returns true if the relay stopped because the lease was lost, along
+// with the reason it was lost. Otherwise, it returns the error returned by run.
+func (r *Relay) term(
+	ctx context.Context,
+	holder string,
+	ttl time.Duration,
+	run func(context.Context) error,
+) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Written by the heartbeat before it exits, and so only read once it has.
+	var lostErr error
+
+	// Renew the lease in the background, and stop the relay as soon as a renewal fails. A renewal which does not
+	// complete before the next is due is treated as a failure, so the relay stops well before the lease expires.
+	heartbeat := make(chan struct{})
+	go func() {
+		defer close(heartbeat)
+
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				renewCtx, cancelRenew := context.WithTimeout(ctx, ttl/3)
+				renewed, err := r.config.Leases.AcquireLease(renewCtx, r.config.LeaseName, holder, ttl)
+				cancelRenew()
+
+				if ctx.Err() != nil {
+					return
+				}
+
+				if err == nil && !renewed {
+					err = errLeaseTaken
+				}
+
+				if err != nil {
+					lostErr = err
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := run(ctx)
+	cancel()
+	<-heartbeat
+
+	if lostErr != nil {
+		return true, lostErr
+	}
+
+	// Release the lease, so that a standby can take over without waiting for it to expire.
+	if err := r.config.Leases.ReleaseLease(context.WithoutCancel(ctx), r.config.LeaseName, holder); err != nil {
+		r.observer.LeaseError(ctx, r.config.LeaseName, fmt.Errorf("failed to release lease: %w", err))
+	}
+
+	return false, err
+}
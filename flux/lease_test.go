@@ -0,0 +1,277 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyLeaseStore is a LeaseStore which fails every request once broken is set.
+type flakyLeaseStore struct {
+	flux.LeaseStore
+	broken atomic.Bool
+}
+
+func (store *flakyLeaseStore) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if store.broken.Load() {
+		return false, errors.New("lease store unavailable")
+	}
+
+	return store.LeaseStore.AcquireLease(ctx, name, holder, ttl)
+}
+
+// leaderLeaseStore is a LeaseStore which records the ID of the relay which last acquired or renewed a lease.
+type leaderLeaseStore struct {
+	flux.LeaseStore
+	id     string
+	leader *atomic.Value
+}
+
+func (store *leaderLeaseStore) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	acquired, err := store.LeaseStore.AcquireLease(ctx, name, holder, ttl)
+	if acquired {
+		store.leader.Store(store.id)
+	}
+
+	return acquired, err
+}
+
+func TestRelay_LeaderElection(t *testing.T) {
+	const (
+		name = "test-relay"
+		ttl  = 90 * time.Millisecond
+	)
+
+	events := flux.NewMemoryEventStore()
+	cursors := flux.NewMemoryCursorStore()
+	leases := flux.NewMemoryLeaseStore()
+
+	var (
+		mu         sync.Mutex
+		dispatched = make(map[string][]flux.Event)
+		leader     atomic.Value
+	)
+
+	newRelay := func(id string, leases flux.LeaseStore) *flux.Relay {
+		dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			dispatched[id] = append(dispatched[id], e)
+			return nil
+		})
+
+		relay := flux.NewRelay(
+			dispatcher,
+			events,
+			flux.WithBackOff(backoff.NewConstantBackOff(5*time.Millisecond)),
+			flux.WithCursor(cursors, name),
+			flux.WithLeaderElection(&leaderLeaseStore{LeaseStore: leases, id: id, leader: &leader}, name, ttl),
+		)
+
+		go func() {
+			_ = relay.Start(context.Background(), flux.StreamRequest{})
+		}()
+
+		return relay
+	}
+
+	// Returns the number of events dispatched by each relay, once count events have been dispatched in total.
+	waitForEvents := func(count int) map[string]int {
+		counts := make(map[string]int)
+
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+
+			total := 0
+			for id, el := range dispatched {
+				counts[id] = len(el)
+				total += len(el)
+			}
+
+			return total >= count
+		}, 5*time.Second, time.Millisecond)
+
+		return counts
+	}
+
+	createEvents := func(count int) {
+		for i := 0; i < count; i++ {
+			_, err := events.CreateEvent(context.Background(), "topic", "key")
+			require.NoError(t, err)
+		}
+	}
+
+	flaky := &flakyLeaseStore{LeaseStore: leases}
+
+	relays := map[string]*flux.Relay{
+		"a": newRelay("a", flaky),
+		"b": newRelay("b", leases),
+	}
+
+	createEvents(10)
+
+	// Only the leader dispatches events.
+	counts := waitForEvents(10)
+	require.Len(t, counts, 1)
+
+	for id := range counts {
+		if id == "a" {
+			// The leader can no longer renew its lease, so it steps down and the standby takes over once it expires.
+			flaky.broken.Store(true)
+		} else {
+			// The leader releases its lease when it is shut down, so the standby takes over immediately.
+			require.NoError(t, relays[id].Shutdown(context.Background()))
+		}
+	}
+
+	start := time.Now()
+
+	// Wait for the standby to take over, so that the new events are not dispatched by the leader before it notices it
+	// has lost its lease.
+	require.Eventually(t, func() bool {
+		for id := range counts {
+			return leader.Load() != id
+		}
+
+		return false
+	}, 2*ttl, time.Millisecond)
+
+	createEvents(10)
+
+	counts = waitForEvents(20)
+	require.Less(t, time.Since(start), 2*ttl)
+	require.Equal(t, map[string]int{"a": 10, "b": 10}, counts)
+
+	// Every event was dispatched exactly once.
+	mu.Lock()
+	defer mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, el := range dispatched {
+		for _, e := range el {
+			require.False(t, seen[e.ID()])
+			seen[e.ID()] = true
+		}
+	}
+
+	for _, relay := range relays {
+		require.NoError(t, relay.Shutdown(context.Background()))
+	}
+}
+
+func TestRelay_LeaderElection_Observer(t *testing.T) {
+	const ttl = 60 * time.Millisecond
+
+	observer := &recordingObserver{}
+	leases := &flakyLeaseStore{LeaseStore: flux.NewMemoryLeaseStore()}
+
+	relay := flux.NewRelay(
+		flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error { return nil }),
+		flux.NewMemoryEventStore(),
+		flux.WithObserver(observer),
+		flux.WithLeaderElection(leases, "test-relay", ttl),
+	)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- relay.Start(context.Background(), flux.StreamRequest{})
+	}()
+
+	requireLeaseEvents := func(expected ...string) {
+		t.Helper()
+
+		require.Eventually(t, func() bool {
+			observer.mu.Lock()
+			defer observer.mu.Unlock()
+
+			return len(observer.leases) >= len(expected) && slices.Equal(observer.leases[:len(expected)], expected)
+		}, 5*time.Second, time.Millisecond)
+	}
+
+	requireLeaseEvents("acquired")
+
+	// The relay fails to renew its lease, and then fails to acquire it again.
+	leases.broken.Store(true)
+	requireLeaseEvents("acquired", "lost", "error")
+
+	// Once the lease store recovers, the relay becomes the leader again.
+	leases.broken.Store(false)
+	require.Eventually(t, func() bool {
+		observer.mu.Lock()
+		defer observer.mu.Unlock()
+
+		return observer.leases[len(observer.leases)-1] == "acquired"
+	}, 5*time.Second, time.Millisecond)
+
+	require.NoError(t, relay.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+}
+
+func TestRelay_LeaderElection_InvalidTTL(t *testing.T) {
+	for _, ttl := range []time.Duration{-time.Second, time.Nanosecond, flux.MinLeaseTTL - 1} {
+		relay := flux.NewRelay(
+			flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error { return nil }),
+			flux.NewMemoryEventStore(),
+			flux.WithLeaderElection(flux.NewMemoryLeaseStore(), "test-relay", ttl),
+		)
+
+		err := relay.Start(context.Background(), flux.StreamRequest{})
+		require.ErrorIs(t, err, flux.ErrInvalidLeaseTTL, "ttl %s", ttl)
+	}
+}
+
+func TestMemoryLeaseStore(t *testing.T) {
+	testLeaseStore(t, flux.NewMemoryLeaseStore())
+}
+
+func testLeaseStore(t *testing.T, leaseStore flux.LeaseStore) {
+	const (
+		name = "test-lease"
+		ttl  = 100 * time.Millisecond
+	)
+
+	ctx := context.Background()
+
+	acquired, err := leaseStore.AcquireLease(ctx, name, "a", ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// The holder can renew the lease, but nobody else can acquire it.
+	acquired, err = leaseStore.AcquireLease(ctx, name, "a", ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	acquired, err = leaseStore.AcquireLease(ctx, name, "b", ttl)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	// Only the holder can release the lease.
+	require.NoError(t, leaseStore.ReleaseLease(ctx, name, "b"))
+
+	acquired, err = leaseStore.AcquireLease(ctx, name, "b", ttl)
+	require.NoError(t, err)
+	require.False(t, acquired)
+
+	require.NoError(t, leaseStore.ReleaseLease(ctx, name, "a"))
+
+	acquired, err = leaseStore.AcquireLease(ctx, name, "b", ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+
+	// The lease can be taken over once it has expired.
+	time.Sleep(ttl)
+
+	acquired, err = leaseStore.AcquireLease(ctx, name, "a", ttl)
+	require.NoError(t, err)
+	require.True(t, acquired)
+}
@@ -0,0 +1,368 @@
+package flux
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewMemoryEventStore returns a new instance of a MemoryEventStore.
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{
+		notify: make(chan struct{}),
+	}
+}
+
+// Compile-time assertions that MemoryEventStore implements the EventStore and EventWaiter interfaces.
+var (
+	_ EventStore  = (*MemoryEventStore)(nil)
+	_ EventWaiter = (*MemoryEventStore)(nil)
+)
+
+// MemoryEventStore is an implementation of an EventStore that keeps its events in memory. It is safe for concurrent use,
+// and is intended for tests and single-process applications.
+type MemoryEventStore struct {
+	mu     sync.RWMutex
+	events []*defaultEvent
+
+	// notify is closed, and replaced, whenever new events are created.
+	notify chan struct{}
+}
+
+func (store *MemoryEventStore) CreateEvent(
+	ctx context.Context,
+	topic string,
+	key string,
+	opts ...EventOption,
+) (Event, error) {
+	events, err := store.CreateEvents(ctx, CreateEventRequest{Topic: topic, Key: key, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	return events[0], nil
+}
+
+// CreateEvents creates several events at once. The events are assigned sequences in the order in which they are given,
+// and are returned in that same order.
+func (store *MemoryEventStore) CreateEvents(ctx context.Context, reqs ...CreateEventRequest) ([]Event, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	// Match the microsecond precision of the timestamps stored by the SQL backed stores.
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	events := make([]Event, 0, len(reqs))
+	for _, req := range reqs {
+		config := NewEventConfig(req.Options...)
+
+		event := &defaultEvent{
+			id:          uuid.NewString(),
+			topic:       EventTopic(req.Topic),
+			sequence:    uint(len(store.events) + 1),
+			key:         req.Key,
+			timestamp:   now,
+			payload:     bytes.Clone(config.Payload),
+			contentType: config.ContentType,
+			traceParent: config.traceParent(ctx),
+		}
+
+		store.events = append(store.events, event)
+		events = append(events, event)
+	}
+
+	// Wake up anyone waiting for new events.
+	close(store.notify)
+	store.notify = make(chan struct{})
+
+	return events, nil
+}
+
+func (store *MemoryEventStore) Head(ctx context.Context) (Event, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if len(store.events) == 0 {
+		return nil, ErrEventNotFound
+	}
+
+	return store.events[len(store.events)-1], nil
+}
+
+func (store *MemoryEventStore) NextEvents(
+	ctx context.Context,
+	from uint,
+	batchSize uint,
+	streamLag time.Duration,
+) ([]Event, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	cutoff := time.Now().Add(-1 * streamLag)
+
+	var events []Event
+	for i := int(from); i < len(store.events) && uint(len(events)) < batchSize; i++ {
+		// Events are ordered by timestamp as well as sequence, so no later events can fall inside the stream lag.
+		if !store.events[i].timestamp.Before(cutoff) {
+			break
+		}
+
+		events = append(events, store.events[i])
+	}
+
+	if len(events) == 0 {
+		return nil, ErrEventNotFound
+	}
+
+	return events, nil
+}
+
+// Wait blocks until the store contains an event with a sequence greater than from, which is older than the stream
+// lag, or the context is cancelled.
+func (store *MemoryEventStore) Wait(ctx context.Context, from uint, streamLag time.Duration) error {
+	for {
+		var next *defaultEvent
+
+		store.mu.RLock()
+		if uint(len(store.events)) > from {
+			next = store.events[from]
+		}
+		notify := store.notify
+		store.mu.RUnlock()
+
+		if next == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-notify:
+			}
+
+			continue
+		}
+
+		// Events are ordered by timestamp as well as sequence, so the next event is the first to leave the stream lag.
+		d := time.Until(next.timestamp.Add(streamLag))
+		if d < 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(d)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// NewMemoryCursorStore returns a new instance of a MemoryCursorStore.
+func NewMemoryCursorStore() *MemoryCursorStore {
+	return &MemoryCursorStore{
+		cursors: make(map[string]*defaultCursor),
+	}
+}
+
+// Compile-time assertion that MemoryCursorStore implements the CursorStore interface.
+var _ CursorStore = (*MemoryCursorStore)(nil)
+
+// MemoryCursorStore is an implementation of a CursorStore that keeps its cursors in memory. It is safe for concurrent
+// use, and is intended for tests and single-process applications.
+type MemoryCursorStore struct {
+	mu      sync.RWMutex
+	cursors map[string]*defaultCursor
+}
+
+func (store *MemoryCursorStore) CreateCursor(ctx context.Context, name string, sequence uint) (Cursor, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	cursor := &defaultCursor{
+		id:        uuid.NewString(),
+		name:      name,
+		sequence:  sequence,
+		createdAt: now,
+		updatedAt: now,
+	}
+
+	store.cursors[cursor.id] = cursor
+
+	copied := *cursor
+	return &copied, nil
+}
+
+func (store *MemoryCursorStore) LookupCursorByID(ctx context.Context, id string) (Cursor, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	cursor, ok := store.cursors[id]
+	if !ok {
+		return nil, ErrCursorNotFound
+	}
+
+	copied := *cursor
+	return &copied, nil
+}
+
+func (store *MemoryCursorStore) LookupCursorByName(ctx context.Context, name string) (Cursor, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	for _, cursor := range store.cursors {
+		if cursor.name == name {
+			copied := *cursor
+			return &copied, nil
+		}
+	}
+
+	return nil, ErrCursorNotFound
+}
+
+func (store *MemoryCursorStore) UpdateCursor(ctx context.Context, id string, sequence uint) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	cursor, ok := store.cursors[id]
+	if !ok {
+		return ErrCursorNotFound
+	}
+
+	cursor.sequence = sequence
+	cursor.updatedAt = time.Now().UTC().Truncate(time.Microsecond)
+
+	return nil
+}
+
+// NewMemoryDeadLetterStore returns a new instance of a MemoryDeadLetterStore.
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{
+		letters: make(map[string]*DeadLetter),
+	}
+}
+
+// Compile-time assertion that MemoryDeadLetterStore implements the DeadLetterStore interface.
+var _ DeadLetterStore = (*MemoryDeadLetterStore)(nil)
+
+// MemoryDeadLetterStore is an implementation of a DeadLetterStore that keeps its dead letters in memory. It is safe for
+// concurrent use, and is intended for tests and single-process applications.
+type MemoryDeadLetterStore struct {
+	mu      sync.RWMutex
+	letters map[string]*DeadLetter
+}
+
+func (store *MemoryDeadLetterStore) DeadLetter(ctx context.Context, letter *DeadLetter) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	copied := *letter
+	store.letters[letter.ID()] = &copied
+
+	return nil
+}
+
+func (store *MemoryDeadLetterStore) ListDeadLetters(ctx context.Context, limit uint) ([]*DeadLetter, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	letters := make([]*DeadLetter, 0, len(store.letters))
+	for _, letter := range store.letters {
+		copied := *letter
+		letters = append(letters, &copied)
+	}
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].Sequence() < letters[j].Sequence()
+	})
+
+	if uint(len(letters)) > limit {
+		letters = letters[:limit]
+	}
+
+	return letters, nil
+}
+
+func (store *MemoryDeadLetterStore) DeleteDeadLetter(ctx context.Context, eventID string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.letters[eventID]; !ok {
+		return ErrDeadLetterNotFound
+	}
+
+	delete(store.letters, eventID)
+
+	return nil
+}
+
+// NewMemoryLeaseStore returns a new instance of a MemoryLeaseStore.
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{
+		leases: make(map[string]memoryLease),
+	}
+}
+
+// Compile-time assertion that MemoryLeaseStore implements the LeaseStore interface.
+var _ LeaseStore = (*MemoryLeaseStore)(nil)
+
+// MemoryLeaseStore is an implementation of a LeaseStore that keeps its leases in memory. It is safe for concurrent use,
+// and is intended for tests and for electing a leader amongst relays in a single process.
+type MemoryLeaseStore struct {
+	mu     sync.Mutex
+	leases map[string]memoryLease
+}
+
+type memoryLease struct {
+	holder    string
+	expiresAt time.Time
+}
+
+func (store *MemoryLeaseStore) AcquireLease(
+	ctx context.Context,
+	name string,
+	holder string,
+	ttl time.Duration,
+) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+
+	lease, ok := store.leases[name]
+	if ok && lease.holder != holder && now.Before(lease.expiresAt) {
+		return false, nil
+	}
+
+	store.leases[name] = memoryLease{holder: holder, expiresAt: now.Add(ttl)}
+
+	return true, nil
+}
+
+func (store *MemoryLeaseStore) ReleaseLease(ctx context.Context, name, holder string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if lease, ok := store.leases[name]; ok && lease.holder == holder {
+		delete(store.leases, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/nickcorin/toolkit/flux/fluxtest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCursorStore(t *testing.T) {
+	testCursorStore(t, flux.NewMemoryCursorStore())
+
+	fluxtest.RunCursorStoreSuite(t, func(t *testing.T) flux.CursorStore {
+		return flux.NewMemoryCursorStore()
+	})
+}
+
+func TestMemoryEventStore(t *testing.T) {
+	testEventStore(t, flux.NewMemoryEventStore())
+
+	fluxtest.RunEventStoreSuite(t, func(t *testing.T) flux.EventStore {
+		return flux.NewMemoryEventStore()
+	})
+
+	t.Run("no more events", func(t *testing.T) {
+		fluxtest.RunEventStoreSuite(t, func(t *testing.T) flux.EventStore {
+			return noMoreEventsStore{flux.NewMemoryEventStore()}
+		})
+	})
+}
+
+// noMoreEventsStore is an event store which returns ErrNoMoreEvents from NextEvents, as documented by EventReader, in
+// place of ErrEventNotFound.
+type noMoreEventsStore struct {
+	*flux.MemoryEventStore
+}
+
+func (store noMoreEventsStore) NextEvents(
+	ctx context.Context,
+	from uint,
+	batchSize uint,
+	streamLag time.Duration,
+) ([]flux.Event, error) {
+	events, err := store.MemoryEventStore.NextEvents(ctx, from, batchSize, streamLag)
+	if errors.Is(err, flux.ErrEventNotFound) {
+		return nil, flux.ErrNoMoreEvents
+	}
+
+	return events, err
+}
+
+func TestMemoryDeadLetterStore(t *testing.T) {
+	testDeadLetterStore(t, flux.NewMemoryDeadLetterStore())
+}
+
+func TestMemoryEventStore_ConcurrentWrites(t *testing.T) {
+	const (
+		writers = 10
+		writes  = 50
+	)
+
+	eventStore := flux.NewMemoryEventStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < writes; j++ {
+				_, err := eventStore.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+				require.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	events, err := eventStore.NextEvents(context.Background(), 0, writers*writes, 0)
+	require.NoError(t, err)
+	require.Len(t, events, writers*writes)
+
+	for i, event := range events {
+		require.Equal(t, uint(i+1), event.Sequence())
+	}
+}
+
+func TestMemoryEventStore_Wait(t *testing.T) {
+	eventStore := flux.NewMemoryEventStore()
+
+	t.Run("context cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := eventStore.Wait(ctx, 0, 0)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("event created", func(t *testing.T) {
+		errs := make(chan error, 1)
+		go func() {
+			errs <- eventStore.Wait(context.Background(), 0, 0)
+		}()
+
+		_, err := eventStore.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("wait did not return")
+		}
+	})
+
+	t.Run("events already exist", func(t *testing.T) {
+		err := eventStore.Wait(context.Background(), 0, 0)
+		require.NoError(t, err)
+	})
+
+	t.Run("events within the stream lag", func(t *testing.T) {
+		const lag = 50 * time.Millisecond
+
+		event, err := eventStore.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		from := event.Sequence() - 1
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err = eventStore.Wait(ctx, from, time.Hour)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// Wait returns once the event leaves the stream lag, at which point it can be read.
+		require.NoError(t, eventStore.Wait(context.Background(), from, lag))
+		require.GreaterOrEqual(t, time.Since(event.Timestamp()), lag)
+
+		events, err := eventStore.NextEvents(context.Background(), from, 1, lag)
+		require.NoError(t, err)
+		require.Equal(t, event.ID(), events[0].ID())
+	})
+}
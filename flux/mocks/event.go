@@ -97,18 +97,23 @@ func (m *MockEventWriter) EXPECT() *MockEventWriterMockRecorder {
 }
 
 // CreateEvent mocks base method.
-func (m *MockEventWriter) CreateEvent(arg0 context.Context, arg1, arg2 string) (flux.Event, error) {
+func (m *MockEventWriter) CreateEvent(arg0 context.Context, arg1, arg2 string, arg3 ...flux.EventOption) (flux.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEvent", arg0, arg1, arg2)
+	varargs := []any{arg0, arg1, arg2}
+	for _, a := range arg3 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateEvent", varargs...)
 	ret0, _ := ret[0].(flux.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateEvent indicates an expected call of CreateEvent.
-func (mr *MockEventWriterMockRecorder) CreateEvent(arg0, arg1, arg2 any) *gomock.Call {
+func (mr *MockEventWriterMockRecorder) CreateEvent(arg0, arg1, arg2 any, arg3 ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockEventWriter)(nil).CreateEvent), arg0, arg1, arg2)
+	varargs := append([]any{arg0, arg1, arg2}, arg3...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockEventWriter)(nil).CreateEvent), varargs...)
 }
 
 // MockEventStore is a mock of EventStore interface.
@@ -135,18 +140,23 @@ func (m *MockEventStore) EXPECT() *MockEventStoreMockRecorder {
 }
 
 // CreateEvent mocks base method.
-func (m *MockEventStore) CreateEvent(arg0 context.Context, arg1, arg2 string) (flux.Event, error) {
+func (m *MockEventStore) CreateEvent(arg0 context.Context, arg1, arg2 string, arg3 ...flux.EventOption) (flux.Event, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEvent", arg0, arg1, arg2)
+	varargs := []any{arg0, arg1, arg2}
+	for _, a := range arg3 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateEvent", varargs...)
 	ret0, _ := ret[0].(flux.Event)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateEvent indicates an expected call of CreateEvent.
-func (mr *MockEventStoreMockRecorder) CreateEvent(arg0, arg1, arg2 any) *gomock.Call {
+func (mr *MockEventStoreMockRecorder) CreateEvent(arg0, arg1, arg2 any, arg3 ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockEventStore)(nil).CreateEvent), arg0, arg1, arg2)
+	varargs := append([]any{arg0, arg1, arg2}, arg3...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEvent", reflect.TypeOf((*MockEventStore)(nil).CreateEvent), varargs...)
 }
 
 // Head mocks base method.
@@ -0,0 +1,217 @@
+package flux
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RelayObserver is notified of a Relay's activity, which allows it to be logged or measured. Observers must be safe
+// for concurrent use, as events may be dispatched concurrently, and should return quickly.
+type RelayObserver interface {
+	// BatchFetched is called when the relay fetches a batch of events after the from sequence.
+	BatchFetched(ctx context.Context, from uint, count int)
+
+	// EventDispatched is called when an event has been dispatched, with the time it took to dispatch.
+	EventDispatched(ctx context.Context, e Event, latency time.Duration)
+
+	// DispatchFailed is called when the dispatcher fails to dispatch an event.
+	DispatchFailed(ctx context.Context, e Event, latency time.Duration, err error)
+
+	// EventFiltered is called when an event is skipped, because it does not match the stream's filters.
+	EventFiltered(ctx context.Context, e Event)
+
+	// GapDetected is called when the relay detects a gap in the sequences of the event stream, between the from and to
+	// sequences.
+	GapDetected(ctx context.Context, from, to uint)
+
+	// RetryScheduled is called when the relay fails, and will retry after the given delay.
+	RetryScheduled(ctx context.Context, err error, next time.Duration)
+
+	// PositionAdvanced is called when the relay's position advances, with how far it lags behind the event store.
+	PositionAdvanced(ctx context.Context, position uint, lag RelayLag)
+
+	// Shutdown is called when the relay has been shut down, once it has flushed its buffer.
+	Shutdown(ctx context.Context)
+
+	// WaitFailed is called when the relay fails to wait for an EventWaiter to signal new events, in which case it
+	// polls the event store instead.
+	WaitFailed(ctx context.Context, err error)
+
+	// LeaseAcquired is called when the relay acquires the named lease, and becomes the leader. See WithLeaderElection.
+	LeaseAcquired(ctx context.Context, name string)
+
+	// LeaseLost is called when the relay fails to renew the named lease, and steps down as the leader.
+	LeaseLost(ctx context.Context, name string, err error)
+
+	// LeaseError is called when the relay fails to acquire or release the named lease.
+	LeaseError(ctx context.Context, name string, err error)
+}
+
+// RelayLag describes how far a Relay lags behind the most recent event in its event store.
+type RelayLag struct {
+	// The number of sequences between the relay's position and the most recent event.
+	Sequences uint
+
+	// The time since the event at the relay's position occurred, or zero if the relay has caught up.
+	Age time.Duration
+}
+
+// multiObserver is a RelayObserver which notifies several observers in turn.
+type multiObserver []RelayObserver
+
+func (m multiObserver) BatchFetched(ctx context.Context, from uint, count int) {
+	for _, o := range m {
+		o.BatchFetched(ctx, from, count)
+	}
+}
+
+func (m multiObserver) EventDispatched(ctx context.Context, e Event, latency time.Duration) {
+	for _, o := range m {
+		o.EventDispatched(ctx, e, latency)
+	}
+}
+
+func (m multiObserver) DispatchFailed(ctx context.Context, e Event, latency time.Duration, err error) {
+	for _, o := range m {
+		o.DispatchFailed(ctx, e, latency, err)
+	}
+}
+
+func (m multiObserver) EventFiltered(ctx context.Context, e Event) {
+	for _, o := range m {
+		o.EventFiltered(ctx, e)
+	}
+}
+
+func (m multiObserver) GapDetected(ctx context.Context, from, to uint) {
+	for _, o := range m {
+		o.GapDetected(ctx, from, to)
+	}
+}
+
+func (m multiObserver) RetryScheduled(ctx context.Context, err error, next time.Duration) {
+	for _, o := range m {
+		o.RetryScheduled(ctx, err, next)
+	}
+}
+
+func (m multiObserver) PositionAdvanced(ctx context.Context, position uint, lag RelayLag) {
+	for _, o := range m {
+		o.PositionAdvanced(ctx, position, lag)
+	}
+}
+
+func (m multiObserver) Shutdown(ctx context.Context) {
+	for _, o := range m {
+		o.Shutdown(ctx)
+	}
+}
+
+func (m multiObserver) WaitFailed(ctx context.Context, err error) {
+	for _, o := range m {
+		o.WaitFailed(ctx, err)
+	}
+}
+
+func (m multiObserver) LeaseAcquired(ctx context.Context, name string) {
+	for _, o := range m {
+		o.LeaseAcquired(ctx, name)
+	}
+}
+
+func (m multiObserver) LeaseLost(ctx context.Context, name string, err error) {
+	for _, o := range m {
+		o.LeaseLost(ctx, name, err)
+	}
+}
+
+func (m multiObserver) LeaseError(ctx context.Context, name string, err error) {
+	for _, o := range m {
+		o.LeaseError(ctx, name, err)
+	}
+}
+
+// Compile-time assertion that SlogObserver implements the RelayObserver interface.
+var _ RelayObserver = (*SlogObserver)(nil)
+
+// NewSlogObserver returns a RelayObserver which logs the relay's activity using the given logger. If the logger is
+// nil, slog.Default() is used.
+//
+// Failures are logged at the error level, lost leases at the warn level, gaps, shutdowns and acquired leases at the
+// info level, and everything else at the debug level.
+func NewSlogObserver(logger *slog.Logger) *SlogObserver {
+	return &SlogObserver{logger: logger}
+}
+
+// SlogObserver is a RelayObserver which logs using a slog.Logger.
+type SlogObserver struct {
+	logger *slog.Logger
+}
+
+func (o *SlogObserver) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	logger := o.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger.Log(ctx, level, msg, args...)
+}
+
+func (o *SlogObserver) BatchFetched(ctx context.Context, from uint, count int) {
+	o.log(ctx, slog.LevelDebug, "fetched events", "from", from, "count", count)
+}
+
+func (o *SlogObserver) EventDispatched(ctx context.Context, e Event, latency time.Duration) {
+	o.log(ctx, slog.LevelDebug, "dispatched event", eventAttrs(e), "latency", latency)
+}
+
+func (o *SlogObserver) DispatchFailed(ctx context.Context, e Event, latency time.Duration, err error) {
+	o.log(ctx, slog.LevelError, "failed to dispatch event", eventAttrs(e), "latency", latency, "error", err)
+}
+
+func (o *SlogObserver) EventFiltered(ctx context.Context, e Event) {
+	o.log(ctx, slog.LevelDebug, "filtered event", eventAttrs(e))
+}
+
+func (o *SlogObserver) GapDetected(ctx context.Context, from, to uint) {
+	o.log(ctx, slog.LevelInfo, "detected gap", "from", from, "to", to)
+}
+
+func (o *SlogObserver) RetryScheduled(ctx context.Context, err error, next time.Duration) {
+	o.log(ctx, slog.LevelError, "relay failed, retrying", "error", err, "next", next)
+}
+
+func (o *SlogObserver) PositionAdvanced(ctx context.Context, position uint, lag RelayLag) {
+	o.log(ctx, slog.LevelDebug, "advanced position", "position", position, "lag_sequences", lag.Sequences,
+		"lag_age", lag.Age)
+}
+
+func (o *SlogObserver) Shutdown(ctx context.Context) {
+	o.log(ctx, slog.LevelInfo, "relay shut down")
+}
+
+func (o *SlogObserver) WaitFailed(ctx context.Context, err error) {
+	o.log(ctx, slog.LevelError, "failed to wait for events, polling instead", "error", err)
+}
+
+func (o *SlogObserver) LeaseAcquired(ctx context.Context, name string) {
+	o.log(ctx, slog.LevelInfo, "acquired lease", "lease", name)
+}
+
+func (o *SlogObserver) LeaseLost(ctx context.Context, name string, err error) {
+	o.log(ctx, slog.LevelWarn, "lost lease", "lease", name, "error", err)
+}
+
+func (o *SlogObserver) LeaseError(ctx context.Context, name string, err error) {
+	o.log(ctx, slog.LevelError, "lease failed", "lease", name, "error", err)
+}
+
+func eventAttrs(e Event) slog.Attr {
+	return slog.Group("event",
+		"id", e.ID(),
+		"topic", string(e.Topic()),
+		"sequence", e.Sequence(),
+		"key", e.Key(),
+	)
+}
@@ -0,0 +1,298 @@
+package flux_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingObserver is a RelayObserver which records the calls made to it.
+type recordingObserver struct {
+	mu         sync.Mutex
+	fetched    []int
+	dispatched []flux.Event
+	failed     []flux.Event
+	filtered   []flux.Event
+	gaps       int
+	retries    int
+	positions  []uint
+	lags       []flux.RelayLag
+	shutdowns  int
+	waitErrors int
+	leases     []string
+}
+
+func (o *recordingObserver) BatchFetched(ctx context.Context, from uint, count int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.fetched = append(o.fetched, count)
+}
+
+func (o *recordingObserver) EventDispatched(ctx context.Context, e flux.Event, latency time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.dispatched = append(o.dispatched, e)
+}
+
+func (o *recordingObserver) DispatchFailed(ctx context.Context, e flux.Event, latency time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.failed = append(o.failed, e)
+}
+
+func (o *recordingObserver) EventFiltered(ctx context.Context, e flux.Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.filtered = append(o.filtered, e)
+}
+
+func (o *recordingObserver) GapDetected(ctx context.Context, from, to uint) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.gaps++
+}
+
+func (o *recordingObserver) RetryScheduled(ctx context.Context, err error, next time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.retries++
+}
+
+func (o *recordingObserver) PositionAdvanced(ctx context.Context, position uint, lag flux.RelayLag) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.positions = append(o.positions, position)
+	o.lags = append(o.lags, lag)
+}
+
+func (o *recordingObserver) Shutdown(ctx context.Context) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.shutdowns++
+}
+
+func (o *recordingObserver) WaitFailed(ctx context.Context, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.waitErrors++
+}
+
+// The lease events are recorded in order, as "acquired", "lost" or "error".
+func (o *recordingObserver) LeaseAcquired(ctx context.Context, name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.leases = append(o.leases, "acquired")
+}
+
+func (o *recordingObserver) LeaseLost(ctx context.Context, name string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.leases = append(o.leases, "lost")
+}
+
+func (o *recordingObserver) LeaseError(ctx context.Context, name string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.leases = append(o.leases, "error")
+}
+
+func TestRelay_Observer(t *testing.T) {
+	events := generateEvents(t, 5)
+
+	// The third event fails on its first attempt, and the last event is filtered out.
+	var failed bool
+	dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+		if e.ID() == events[2].ID() && !failed {
+			failed = true
+			return errors.New("temporary failure")
+		}
+
+		return nil
+	})
+
+	filter := flux.EventFilterFunc(func(e flux.Event) bool {
+		return e.ID() != events[4].ID()
+	})
+
+	observer := &recordingObserver{}
+	reader := &staticEventReader{events: events}
+
+	relay := flux.NewRelay(
+		dispatcher,
+		reader,
+		flux.WithBackOff(backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 1)),
+		flux.WithBufferSize(2),
+		flux.WithObserver(observer),
+	)
+
+	err := runRelay(t, relay, reader, flux.StreamRequest{Filters: []flux.EventFilter{filter}})
+	require.NoError(t, err)
+
+	require.Equal(t, []int{2, 2, 2, 1}, observer.fetched)
+	require.Equal(t, events[:4], observer.dispatched)
+	require.Equal(t, []flux.Event{events[2]}, observer.failed)
+	require.Equal(t, []flux.Event{events[4]}, observer.filtered)
+	require.Equal(t, 1, observer.retries)
+	require.Equal(t, 1, observer.shutdowns)
+
+	// The relay lags behind the event store until it fetches the final, partial, batch.
+	require.Equal(t, []uint{2, 4, 5}, observer.positions)
+	require.Equal(t, uint(3), observer.lags[0].Sequences)
+	require.Greater(t, observer.lags[0].Age, time.Duration(0))
+	require.Equal(t, uint(1), observer.lags[1].Sequences)
+	require.Equal(t, flux.RelayLag{}, observer.lags[2])
+}
+
+// waitingEventReader is a staticEventReader which is an EventWaiter, whose Wait method fails with err.
+type waitingEventReader struct {
+	*staticEventReader
+	err error
+}
+
+func (r *waitingEventReader) Wait(ctx context.Context, from uint, streamLag time.Duration) error {
+	return r.err
+}
+
+func TestRelay_Observer_WaitFailed(t *testing.T) {
+	run := func(t *testing.T, err error) *recordingObserver {
+		observer := &recordingObserver{}
+		reader := &waitingEventReader{staticEventReader: &staticEventReader{}, err: err}
+
+		relay := flux.NewRelay(
+			flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error { return nil }),
+			reader,
+			flux.WithObserver(observer),
+			flux.WithPollInterval(time.Millisecond),
+		)
+
+		errc := make(chan error, 1)
+		go func() {
+			errc <- relay.Start(context.Background(), flux.StreamRequest{})
+		}()
+
+		// Let the relay poll the event store several times.
+		<-reader.Idle()
+		time.Sleep(20 * time.Millisecond)
+
+		require.NoError(t, relay.Shutdown(context.Background()))
+		require.NoError(t, <-errc)
+
+		observer.mu.Lock()
+		defer observer.mu.Unlock()
+
+		return observer
+	}
+
+	t.Run("reports failures", func(t *testing.T) {
+		observer := run(t, errors.New("connection lost"))
+		require.Positive(t, observer.waitErrors)
+	})
+
+	t.Run("ignores unsupported waits", func(t *testing.T) {
+		observer := run(t, fmt.Errorf("%w: notifications are not enabled", flux.ErrWaitNotSupported))
+		require.Zero(t, observer.waitErrors)
+	})
+}
+
+func TestRelay_DefaultObserver(t *testing.T) {
+	var buf bytes.Buffer
+
+	// The default observer logs using the default logger.
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	events := generateEvents(t, 3)
+	reader := &staticEventReader{events: events}
+
+	relay := flux.NewRelay(
+		flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error { return nil }),
+		reader,
+		flux.WithBufferSize(2),
+	)
+
+	err := runRelay(t, relay, reader, flux.StreamRequest{})
+	require.NoError(t, err)
+
+	// The lag is only looked up for configured observers.
+	logs := buf.String()
+	require.Contains(t, logs, `msg="advanced position" position=2 lag_sequences=0 lag_age=0s`)
+	require.Contains(t, logs, `msg="advanced position" position=3 lag_sequences=0 lag_age=0s`)
+}
+
+func TestSlogObserver(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	observer := flux.NewSlogObserver(logger)
+
+	e := generateEvents(t, 1)[0]
+
+	observer.EventDispatched(context.Background(), e, time.Millisecond)
+	observer.DispatchFailed(context.Background(), e, time.Millisecond, errors.New("boom"))
+	observer.PositionAdvanced(context.Background(), 5, flux.RelayLag{Sequences: 2, Age: time.Second})
+	observer.LeaseAcquired(context.Background(), "test-relay")
+	observer.LeaseLost(context.Background(), "test-relay", errors.New("expired"))
+
+	logs := buf.String()
+	require.Contains(t, logs, `level=DEBUG msg="dispatched event" event.id=`+e.ID())
+	require.Contains(t, logs, `level=ERROR msg="failed to dispatch event" event.id=`+e.ID())
+	require.Contains(t, logs, "error=boom")
+	require.Contains(t, logs, "position=5 lag_sequences=2 lag_age=1s")
+	require.Contains(t, logs, `level=INFO msg="acquired lease" lease=test-relay`)
+	require.Contains(t, logs, `level=WARN msg="lost lease" lease=test-relay error=expired`)
+}
+
+func TestPrometheusObserver(t *testing.T) {
+	reg := prometheus.NewRegistry()
+
+	observer, err := flux.NewPrometheusObserver(reg, flux.WithPrometheusLabels(prometheus.Labels{"relay": "test"}))
+	require.NoError(t, err)
+
+	// Registering the same metrics twice fails.
+	_, err = flux.NewPrometheusObserver(reg, flux.WithPrometheusLabels(prometheus.Labels{"relay": "test"}))
+	require.Error(t, err)
+
+	events := generateEvents(t, 3)
+
+	observer.EventDispatched(context.Background(), events[0], 20*time.Millisecond)
+	observer.EventDispatched(context.Background(), events[1], 30*time.Millisecond)
+	observer.DispatchFailed(context.Background(), events[2], time.Second, errors.New("boom"))
+	observer.PositionAdvanced(context.Background(), 2, flux.RelayLag{Sequences: 8, Age: 90 * time.Second})
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			require.Equal(t, "test", metric.GetLabel()[0].GetValue())
+
+			switch {
+			case metric.GetCounter() != nil:
+				values[family.GetName()] += metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				values[family.GetName()] += metric.GetGauge().GetValue()
+			case metric.GetHistogram() != nil:
+				values[family.GetName()] += float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	require.Equal(t, 2.0, values["flux_relay_events_dispatched_total"])
+	require.Equal(t, 1.0, values["flux_relay_dispatch_failures_total"])
+	require.Equal(t, 3.0, values["flux_relay_dispatch_duration_seconds"])
+	require.Equal(t, 2.0, values["flux_relay_position"])
+	require.Equal(t, 8.0, values["flux_relay_lag_sequences"])
+	require.Equal(t, 90.0, values["flux_relay_lag_seconds"])
+}
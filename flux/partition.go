@@ -0,0 +1,116 @@
+package flux
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// flushPartitioned dispatches the buffered events concurrently, in lanes keyed by the events' keys, so that events with
+// the same key are dispatched in order.
+//
+// The position only advances to the highest sequence below which every event has been dispatched. Events beyond it
+// which were dispatched by other lanes are remembered, so that they are not dispatched again when the stream retries.
+func (s *stream) flushPartitioned(ctx context.Context, d Dispatcher) error {
+	if s.completed == nil {
+		s.completed = make(map[uint]bool)
+	}
+
+	var (
+		planned []Event
+		lanes   = make([][]Event, s.partitions)
+		gapErr  error
+	)
+
+	// Gaps and filters are handled in sequence order, before any events are dispatched.
+	from := s.position
+	for _, e := range s.buffer {
+		if e.Sequence() != from+1 {
+			if gapErr = s.handleGap(ctx, from, e); gapErr != nil {
+				break
+			}
+		}
+
+		from = e.Sequence()
+		planned = append(planned, e)
+
+		if s.completed[e.Sequence()] {
+			continue
+		}
+
+		if !s.shouldDispatch(e) {
+			s.observer.EventFiltered(ctx, e)
+			s.completed[e.Sequence()] = true
+			continue
+		}
+
+		lane := partition(e.Key(), s.partitions)
+		lanes[lane] = append(lanes[lane], e)
+	}
+
+	s.buffer = s.buffer[:0]
+
+	var (
+		wg   sync.WaitGroup
+		done = make([]int, len(lanes))
+		errs = make([]error, len(lanes))
+	)
+
+	for i, lane := range lanes {
+		if len(lane) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, lane []Event) {
+			defer wg.Done()
+
+			// A lane stops at its first failure, so that its later events are not dispatched out of order.
+			for _, e := range lane {
+				if err := s.dispatch(ctx, e, d); err != nil {
+					errs[i] = err
+					return
+				}
+
+				done[i]++
+			}
+		}(i, lane)
+	}
+
+	wg.Wait()
+
+	for i, lane := range lanes {
+		for _, e := range lane[:done[i]] {
+			s.completed[e.Sequence()] = true
+		}
+	}
+
+	// Advance to the highest sequence below which every event has been dispatched.
+	for _, e := range planned {
+		if !s.completed[e.Sequence()] {
+			break
+		}
+
+		delete(s.completed, e.Sequence())
+		s.position = e.Sequence()
+		s.positionTimestamp = e.Timestamp()
+		s.gapDetectedAt = time.Time{}
+	}
+
+	// A failure to dispatch takes precedence over the gap, which is detected again when the stream retries.
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	return gapErr
+}
+
+// partition returns the lane, out of n, that events with the given key are dispatched in.
+func partition(key string, n uint) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(n))
+}
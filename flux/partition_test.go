@@ -0,0 +1,142 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/nickcorin/toolkit/flux/mocks"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRelay_Partitions(t *testing.T) {
+	t.Run("preserves ordering per key", func(t *testing.T) {
+		events := generateEvents(t, 64)
+		for i, e := range events {
+			e.(*testEvent).key = fmt.Sprintf("key-%d", i%16)
+		}
+
+		var (
+			mu          sync.Mutex
+			dispatched  = make(map[string][]flux.Event)
+			inFlight    int
+			maxInFlight int
+		)
+
+		dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			mu.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			inFlight--
+			dispatched[e.Key()] = append(dispatched[e.Key()], e)
+			return nil
+		})
+
+		reader := &staticEventReader{events: events}
+
+		relay := flux.NewRelay(
+			dispatcher,
+			reader,
+			flux.WithBackOff(&backoff.StopBackOff{}),
+			flux.WithBufferSize(32),
+			flux.WithPartitions(4),
+		)
+
+		err := runRelay(t, relay, reader, flux.StreamRequest{})
+		require.NoError(t, err)
+
+		require.Greater(t, maxInFlight, 1)
+		require.LessOrEqual(t, maxInFlight, 4)
+
+		for i := 0; i < 16; i++ {
+			key := fmt.Sprintf("key-%d", i)
+
+			var want []flux.Event
+			for _, e := range events {
+				if e.Key() == key {
+					want = append(want, e)
+				}
+			}
+
+			require.Equal(t, want, dispatched[key])
+		}
+	})
+
+	t.Run("advances to the completed watermark", func(t *testing.T) {
+		const cursorName = "test-relay"
+
+		events := generateEvents(t, 6)
+		for i, e := range events {
+			e.(*testEvent).key = fmt.Sprintf("key-%d", i)
+		}
+
+		var (
+			mu       sync.Mutex
+			failed   bool
+			attempts = make(map[string]int)
+		)
+
+		dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			attempts[e.ID()]++
+
+			// The second event fails on its first attempt.
+			if e.ID() == events[1].ID() && !failed {
+				failed = true
+				return errors.New("temporary failure")
+			}
+
+			return nil
+		})
+
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: "cursor-id", name: cursorName}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), cursorName).Return(cursor, nil)
+		gomock.InOrder(
+			// Only the first event is below the failed event, however many other events were dispatched.
+			cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(1)).Return(nil),
+			cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(6)).Return(nil),
+		)
+
+		reader := &staticEventReader{events: events}
+
+		relay := flux.NewRelay(
+			dispatcher,
+			reader,
+			flux.WithBackOff(backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 2)),
+			flux.WithBufferSize(6),
+			flux.WithPartitions(3),
+			flux.WithCursor(cursors, cursorName),
+		)
+
+		err := runRelay(t, relay, reader, flux.StreamRequest{})
+		require.NoError(t, err)
+
+		// Events which were dispatched beyond the failed event are not dispatched again.
+		for i, e := range events {
+			if i == 1 {
+				require.Equal(t, 2, attempts[e.ID()])
+			} else {
+				require.Equal(t, 1, attempts[e.ID()], "event %d", i)
+			}
+		}
+	})
+}
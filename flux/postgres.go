@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/nickcorin/toolkit/sqlkit"
@@ -70,23 +72,194 @@ func scanCursor(s sqlkit.Scannable) (*defaultCursor, error) {
 }
 
 // NewPostgresEventStore creates a new instance of a PostgresEventStore.
-func NewPostgresEventStore(conn *sql.DB, tableName string) *PostgresEventStore {
-	return &PostgresEventStore{conn: conn, tableName: tableName}
+func NewPostgresEventStore(conn *sql.DB, tableName string, opts ...PostgresEventStoreOption) *PostgresEventStore {
+	var config PostgresEventStoreConfig
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	store := &PostgresEventStore{conn: conn, db: conn, tableName: tableName, config: config}
+
+	if config.NotifyChannel != "" {
+		store.listener = newPostgresListener(conn, config.NotifyChannel)
+	}
+
+	return store
 }
 
+// Compile-time assertions that PostgresEventStore implements the EventStore and EventWaiter interfaces.
+var (
+	_ EventStore  = (*PostgresEventStore)(nil)
+	_ EventWaiter = (*PostgresEventStore)(nil)
+)
+
 // PostgresEventStore is an implementation of an EventStore that uses a PostgreSQL database as its storage backend.
 type PostgresEventStore struct {
-	conn      *sql.DB
+	conn      sqlkit.Querier
+	db        *sql.DB
 	tableName string
+	config    PostgresEventStoreConfig
+
+	// Listens for notifications of new events, if notifications are enabled. It is shared with copies of the store.
+	listener *postgresListener
+}
+
+type PostgresEventStoreConfig struct {
+	// If set, writers serialise the allocation of sequences using a transaction-scoped advisory lock, which is held
+	// until the writing transaction commits or rolls back.
+	//
+	// Sequences allocated by a serial column are not guaranteed to become visible in order: a transaction may commit an
+	// event with a lower sequence after a reader has already moved past it, and the event is then never read. Holding
+	// the lock until commit guarantees that events become visible in sequence order, at the cost of only allowing one
+	// transaction at a time to write events.
+	CommitOrdered bool
+
+	// If set, writers send a notification on this channel whenever they create events, and Wait listens for them.
+	NotifyChannel string
+}
+
+// PostgresEventStoreOption is an interface that allows for functional options to be applied to a
+// PostgresEventStoreConfig.
+type PostgresEventStoreOption interface {
+	Apply(*PostgresEventStoreConfig)
+}
+
+// PostgresEventStoreOptionFunc is a function type that implements the PostgresEventStoreOption interface.
+type PostgresEventStoreOptionFunc func(*PostgresEventStoreConfig)
+
+// Apply applies the function to the event store.
+func (f PostgresEventStoreOptionFunc) Apply(config *PostgresEventStoreConfig) {
+	f(config)
+}
+
+// WithCommitOrderedSequences ensures that events become visible to readers in sequence order, so that a Relay never
+// misses an event that commits late. See PostgresEventStoreConfig.CommitOrdered.
+func WithCommitOrderedSequences() PostgresEventStoreOption {
+	return PostgresEventStoreOptionFunc(func(config *PostgresEventStoreConfig) {
+		config.CommitOrdered = true
+	})
 }
 
-func (store *PostgresEventStore) CreateEvent(ctx context.Context, topic, key string) (Event, error) {
+// WithNotifications makes writers send a notification on the given channel whenever they create events, which allows
+// readers such as a Relay to wait for new events without polling. See PostgresEventStore.Wait.
+//
+// Listening for notifications requires the pgx driver.
+func WithNotifications(channel string) PostgresEventStoreOption {
+	return PostgresEventStoreOptionFunc(func(config *PostgresEventStoreConfig) {
+		config.NotifyChannel = channel
+	})
+}
+
+// WithTx returns a copy of the store which runs its queries against the given transaction (or any other
+// sqlkit.Querier), rather than the store's own connection.
+//
+// This allows events to be written atomically alongside other changes, following the transactional outbox pattern.
+// Events created through the returned store only become visible to readers, such as a Relay, once the transaction
+// commits, and are discarded if it rolls back.
+func (store *PostgresEventStore) WithTx(tx sqlkit.Querier) *PostgresEventStore {
+	return &PostgresEventStore{
+		conn:      tx,
+		db:        store.db,
+		tableName: store.tableName,
+		config:    store.config,
+		listener:  store.listener,
+	}
+}
+
+func (store *PostgresEventStore) CreateEvent(
+	ctx context.Context,
+	topic string,
+	key string,
+	opts ...EventOption,
+) (Event, error) {
+	events, err := store.CreateEvents(ctx, CreateEventRequest{Topic: topic, Key: key, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	return events[0], nil
+}
+
+// CreateEventRequest describes an event to be created by CreateEvents.
+type CreateEventRequest struct {
+	Topic   string
+	Key     string
+	Options []EventOption
+}
+
+// CreateEvents creates several events using a single statement. The events are assigned sequences in the order in
+// which they are given, and are returned in that same order.
+func (store *PostgresEventStore) CreateEvents(ctx context.Context, reqs ...CreateEventRequest) ([]Event, error) {
+	if len(reqs) == 0 {
+		return nil, nil
+	}
+
+	const columnCount = 6
+
+	var (
+		now    = time.Now().UTC()
+		values = make([]string, 0, len(reqs))
+		args   = make([]interface{}, 0, len(reqs)*columnCount)
+	)
+
+	for i, req := range reqs {
+		config := NewEventConfig(req.Options...)
+
+		offset := i * columnCount
+		values = append(values, fmt.Sprintf(
+			"(uuid_generate_v4(), $%d::text, $%d::text, $%d::timestamp, $%d::bytea, $%d::text, $%d::text)",
+			offset+1, offset+2, offset+3, offset+4, offset+5, offset+6,
+		))
+		args = append(args, req.Key, req.Topic, now, config.Payload, config.ContentType, config.traceParent(ctx))
+	}
+
 	query := `
-	INSERT INTO ` + store.tableName + ` (id, key, topic, timestamp)
-	VALUES (uuid_generate_v4(), $1, $2, $3)
-	RETURNING id, topic, sequence, key, timestamp`
+	INSERT INTO ` + store.tableName + ` (id, key, topic, timestamp, payload, content_type, trace_parent)
+	VALUES ` + strings.Join(values, ", ") + `
+	RETURNING id, topic, sequence, key, timestamp, payload, content_type, trace_parent`
+
+	if store.config.CommitOrdered {
+		// The lock must be taken before any sequences are allocated, so it is selected from before the values are.
+		query = `
+		WITH sequence_lock AS (SELECT pg_advisory_xact_lock(hashtext('` + store.tableName + `')))
+		INSERT INTO ` + store.tableName + ` (id, key, topic, timestamp, payload, content_type, trace_parent)
+		SELECT v.* FROM sequence_lock, (VALUES ` + strings.Join(values, ", ") + `)
+			AS v (id, key, topic, timestamp, payload, content_type, trace_parent)
+		RETURNING id, topic, sequence, key, timestamp, payload, content_type, trace_parent`
+	}
+
+	rows, err := store.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query context: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0, len(reqs))
+	for rows.Next() {
+		event, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	// Notifications sent from within a transaction are only delivered once it commits.
+	if store.config.NotifyChannel != "" {
+		if _, err := store.conn.ExecContext(ctx, "SELECT pg_notify($1, '')", store.config.NotifyChannel); err != nil {
+			return nil, fmt.Errorf("notify: %w", err)
+		}
+	}
+
+	// Postgres does not guarantee the order of the returned rows, but sequences are allocated in insertion order.
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Sequence() < events[j].Sequence()
+	})
 
-	return scanEvent(store.conn.QueryRowContext(ctx, query, key, topic, time.Now().UTC()))
+	return events, nil
 }
 
 func (store *PostgresEventStore) Head(ctx context.Context) (Event, error) {
@@ -108,6 +281,7 @@ func (store *PostgresEventStore) NextEvents(
 	if err != nil {
 		return nil, fmt.Errorf("query context: %w", err)
 	}
+	defer rows.Close()
 
 	var events []Event
 	for rows.Next() {
@@ -118,6 +292,10 @@ func (store *PostgresEventStore) NextEvents(
 		events = append(events, event)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
 	if len(events) == 0 {
 		return nil, ErrEventNotFound
 	}
@@ -125,10 +303,65 @@ func (store *PostgresEventStore) NextEvents(
 	return events, nil
 }
 
+// Wait blocks until there is an event with a sequence greater than from, which is older than the stream lag, or the
+// context is cancelled. It returns ErrWaitNotSupported if notifications are not enabled, see WithNotifications, or
+// another error if the store stops listening for notifications while waiting.
+func (store *PostgresEventStore) Wait(ctx context.Context, from uint, streamLag time.Duration) error {
+	if store.listener == nil {
+		return fmt.Errorf("%w: notifications are not enabled", ErrWaitNotSupported)
+	}
+
+	// Subscribe before checking for events, so that no notification is missed in between.
+	notify, err := store.listener.subscribe()
+	if err != nil {
+		return err
+	}
+
+	var earliest sql.NullTime
+
+	query := "SELECT min(timestamp) FROM " + store.tableName + " WHERE sequence > $1"
+	if err := store.db.QueryRowContext(ctx, query, from).Scan(&earliest); err != nil {
+		return fmt.Errorf("query row context: %w", err)
+	}
+
+	if earliest.Valid {
+		// Events are only read once they are older than the stream lag, as in NextEvents, so wait for the earliest of
+		// them to leave it. No notification is sent when that happens.
+		timer := time.NewTimer(time.Until(earliest.Time.Add(streamLag)))
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-notify.done:
+		return notify.err
+	}
+}
+
+// Close stops listening for notifications, if notifications are enabled.
+func (store *PostgresEventStore) Close() error {
+	if store.listener != nil {
+		store.listener.close()
+	}
+
+	return nil
+}
+
 func scanEvent(s sqlkit.Scannable) (*defaultEvent, error) {
 	var event defaultEvent
 
-	err := s.Scan(&event.id, &event.topic, &event.sequence, &event.key, &event.timestamp)
+	err := s.Scan(
+		&event.id, &event.topic, &event.sequence, &event.key, &event.timestamp, &event.payload, &event.contentType,
+		&event.traceParent,
+	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrEventNotFound
@@ -139,3 +372,149 @@ func scanEvent(s sqlkit.Scannable) (*defaultEvent, error) {
 
 	return &event, nil
 }
+
+// NewPostgresDeadLetterStore returns a new instance of a PostgresDeadLetterStore.
+func NewPostgresDeadLetterStore(conn *sql.DB, tableName string) *PostgresDeadLetterStore {
+	return &PostgresDeadLetterStore{conn: conn, tableName: tableName}
+}
+
+// Compile-time assertion that PostgresDeadLetterStore implements the DeadLetterStore interface.
+var _ DeadLetterStore = (*PostgresDeadLetterStore)(nil)
+
+// PostgresDeadLetterStore is a DeadLetterStore that uses a PostgreSQL database as its storage backend. Each dead letter
+// holds a copy of its event, so it is unaffected by changes to the event store.
+type PostgresDeadLetterStore struct {
+	conn      *sql.DB
+	tableName string
+}
+
+// DeadLetter stores the dead letter, replacing any existing dead letter for the same event.
+func (store *PostgresDeadLetterStore) DeadLetter(ctx context.Context, letter *DeadLetter) error {
+	query := `
+	INSERT INTO ` + store.tableName + ` (
+		event_id, topic, sequence, key, timestamp, payload, content_type, trace_parent, cause, attempts,
+		dead_lettered_at
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	ON CONFLICT (event_id) DO UPDATE SET
+		cause = excluded.cause, attempts = excluded.attempts, dead_lettered_at = excluded.dead_lettered_at`
+
+	_, err := store.conn.ExecContext(ctx, query,
+		letter.ID(), letter.Topic(), letter.Sequence(), letter.Key(), letter.Timestamp().UTC(), letter.Payload(),
+		letter.ContentType(), letter.TraceParent(), letter.Cause, letter.Attempts, letter.DeadLetteredAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("insert dead letter: %w", err)
+	}
+
+	return nil
+}
+
+func (store *PostgresDeadLetterStore) ListDeadLetters(ctx context.Context, limit uint) ([]*DeadLetter, error) {
+	query := "SELECT * FROM " + store.tableName + " ORDER BY sequence ASC LIMIT $1"
+
+	rows, err := store.conn.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query context: %w", err)
+	}
+	defer rows.Close()
+
+	var letters []*DeadLetter
+	for rows.Next() {
+		var (
+			event  defaultEvent
+			letter DeadLetter
+		)
+
+		err := rows.Scan(
+			&event.id, &event.topic, &event.sequence, &event.key, &event.timestamp, &event.payload,
+			&event.contentType, &letter.Cause, &letter.Attempts, &letter.DeadLetteredAt, &event.traceParent,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan dead letter: %w", err)
+		}
+
+		letter.Event = &event
+		letters = append(letters, &letter)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+
+	return letters, nil
+}
+
+func (store *PostgresDeadLetterStore) DeleteDeadLetter(ctx context.Context, eventID string) error {
+	query := "DELETE FROM " + store.tableName + " WHERE event_id = $1"
+
+	res, err := store.conn.ExecContext(ctx, query, eventID)
+	if err != nil {
+		return fmt.Errorf("delete dead letter: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+
+	if n == 0 {
+		return ErrDeadLetterNotFound
+	}
+
+	return nil
+}
+
+// NewPostgresLeaseStore returns a new instance of a PostgresLeaseStore.
+func NewPostgresLeaseStore(conn *sql.DB, tableName string) *PostgresLeaseStore {
+	return &PostgresLeaseStore{conn: conn, tableName: tableName}
+}
+
+// Compile-time assertion that PostgresLeaseStore implements the LeaseStore interface.
+var _ LeaseStore = (*PostgresLeaseStore)(nil)
+
+// PostgresLeaseStore is a LeaseStore that uses a PostgreSQL database as its storage backend. Lease expiry is measured
+// using the database's clock, so the holders' clocks do not need to agree.
+type PostgresLeaseStore struct {
+	conn      *sql.DB
+	tableName string
+}
+
+func (store *PostgresLeaseStore) AcquireLease(
+	ctx context.Context,
+	name string,
+	holder string,
+	ttl time.Duration,
+) (bool, error) {
+	// The existing row is only updated if it belongs to the holder, or has expired. Otherwise, no row is returned.
+	query := `
+	INSERT INTO ` + store.tableName + ` (name, holder, expires_at)
+	VALUES ($1, $2, now() + make_interval(secs => $3))
+	ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+	WHERE ` + store.tableName + `.holder = excluded.holder OR ` + store.tableName + `.expires_at < now()
+	RETURNING holder`
+
+	var current string
+
+	err := store.conn.QueryRowContext(ctx, query, name, holder, ttl.Seconds()).Scan(&current)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("acquire lease: %w", err)
+	}
+
+	return true, nil
+}
+
+func (store *PostgresLeaseStore) ReleaseLease(ctx context.Context, name, holder string) error {
+	query := "DELETE FROM " + store.tableName + " WHERE name = $1 AND holder = $2"
+
+	_, err := store.conn.ExecContext(ctx, query, name, holder)
+	if err != nil {
+		return fmt.Errorf("release lease: %w", err)
+	}
+
+	return nil
+}
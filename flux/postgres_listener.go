@@ -0,0 +1,145 @@
+package flux
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// postgresListener holds a dedicated connection which listens on a notification channel, and wakes up subscribers
+// whenever a notification arrives.
+type postgresListener struct {
+	db      *sql.DB
+	channel string
+
+	mu      sync.Mutex
+	running bool
+	closed  bool
+	cancel  context.CancelFunc
+
+	// notify is closed, and replaced, whenever a notification arrives or the listener stops.
+	notify *postgresNotification
+}
+
+// postgresNotification wakes up the subscribers which are waiting for the next notification.
+type postgresNotification struct {
+	// done is closed once a notification arrives, or the listener stops.
+	done chan struct{}
+
+	// The error which stopped the listener, if it stopped unexpectedly. It is only set before done is closed.
+	err error
+}
+
+func newPostgresListener(db *sql.DB, channel string) *postgresListener {
+	return &postgresListener{
+		db:      db,
+		channel: channel,
+		notify:  &postgresNotification{done: make(chan struct{})},
+	}
+}
+
+// subscribe returns the next notification, which wakes up its subscribers once it arrives. It starts listening if the
+// listener is not already doing so.
+func (l *postgresListener) subscribe() (*postgresNotification, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, errors.New("listener is closed")
+	}
+
+	if !l.running {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		conn, err := l.listen(ctx)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		l.running = true
+		l.cancel = cancel
+
+		go l.run(ctx, conn)
+	}
+
+	return l.notify, nil
+}
+
+// listen returns a connection which is listening on the listener's channel.
+func (l *postgresListener) listen(ctx context.Context) (*sql.Conn, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+
+	return conn, nil
+}
+
+// run wakes up subscribers whenever a notification arrives, until the context is cancelled or the connection fails.
+func (l *postgresListener) run(ctx context.Context, conn *sql.Conn) {
+	err := conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unsupported driver connection %T, notifications require the pgx driver", driverConn)
+		}
+
+		for {
+			if _, err := c.Conn().WaitForNotification(ctx); err != nil {
+				return err
+			}
+
+			l.broadcast(nil)
+		}
+	})
+
+	// Subscribers are told why the listener stopped, unless it was closed.
+	if ctx.Err() == nil {
+		err = fmt.Errorf("stopped listening on %q: %w", l.channel, err)
+	} else {
+		err = nil
+	}
+
+	// The connection may be left in an unknown state, so it is not returned to the pool.
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = conn.Close()
+
+	l.mu.Lock()
+	l.running = false
+	l.cancel()
+	l.mu.Unlock()
+
+	// Wake up subscribers, so that they check for events and subscribe again.
+	l.broadcast(err)
+}
+
+func (l *postgresListener) broadcast(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.notify.err = err
+	close(l.notify.done)
+	l.notify = &postgresNotification{done: make(chan struct{})}
+}
+
+func (l *postgresListener) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+
+	if l.running {
+		l.cancel()
+	}
+}
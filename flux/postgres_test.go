@@ -3,9 +3,11 @@ package flux_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nickcorin/toolkit/flux"
+	"github.com/nickcorin/toolkit/flux/fluxtest"
 	"github.com/nickcorin/toolkit/sqlkit"
 	"github.com/stretchr/testify/require"
 )
@@ -16,6 +18,13 @@ func TestPostgresCursorStore(t *testing.T) {
 	require.NotNil(t, conn)
 
 	testCursorStore(t, flux.NewPostgresCursorStore(conn, "cursors"))
+
+	fluxtest.RunCursorStoreSuite(t, func(t *testing.T) flux.CursorStore {
+		conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+		require.NoError(t, err)
+
+		return flux.NewPostgresCursorStore(conn, "cursors")
+	})
 }
 
 func testCursorStore(t *testing.T, cursorStore flux.CursorStore) {
@@ -67,6 +76,13 @@ func TestPostgresEventStore(t *testing.T) {
 	require.NotNil(t, conn)
 
 	testEventStore(t, flux.NewPostgresEventStore(conn, "events"))
+
+	fluxtest.RunEventStoreSuite(t, func(t *testing.T) flux.EventStore {
+		conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+		require.NoError(t, err)
+
+		return flux.NewPostgresEventStore(conn, "events")
+	})
 }
 
 func testEventStore(t *testing.T, eventStore flux.EventStore) {
@@ -134,4 +150,301 @@ func testEventStore(t *testing.T, eventStore flux.EventStore) {
 
 		require.Equal(t, event, storedEvents[len(storedEvents)-1])
 	})
+
+	t.Run("create an event with a payload", func(t *testing.T) {
+		payload := []byte(`{"name":"test"}`)
+
+		event, err := eventStore.CreateEvent(
+			context.Background(),
+			topicA.String(),
+			keyA,
+			flux.WithPayload("application/json", payload),
+		)
+		require.NoError(t, err)
+		require.Equal(t, payload, event.Payload())
+		require.Equal(t, "application/json", event.ContentType())
+
+		head, err := eventStore.Head(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, payload, head.Payload())
+		require.Equal(t, "application/json", head.ContentType())
+	})
+}
+
+func TestPostgresEventStore_WithTx(t *testing.T) {
+	conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+
+	eventStore := flux.NewPostgresEventStore(conn, "events")
+
+	t.Run("rolled back events are discarded", func(t *testing.T) {
+		tx, err := conn.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		_, err = eventStore.WithTx(tx).CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		require.NoError(t, tx.Rollback())
+
+		_, err = eventStore.Head(context.Background())
+		require.ErrorIs(t, err, flux.ErrEventNotFound)
+	})
+
+	t.Run("events are only visible once committed", func(t *testing.T) {
+		tx, err := conn.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		event, err := eventStore.WithTx(tx).CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		_, err = eventStore.Head(context.Background())
+		require.ErrorIs(t, err, flux.ErrEventNotFound)
+
+		require.NoError(t, tx.Commit())
+
+		head, err := eventStore.Head(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, event.ID(), head.ID())
+	})
+
+	t.Run("bulk create events", func(t *testing.T) {
+		tx, err := conn.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		reqs := []flux.CreateEventRequest{
+			{Topic: uuid.NewString(), Key: uuid.NewString()},
+			{Topic: uuid.NewString(), Key: uuid.NewString()},
+			{
+				Topic:   uuid.NewString(),
+				Key:     uuid.NewString(),
+				Options: []flux.EventOption{flux.WithPayload("text/plain", []byte("test"))},
+			},
+		}
+
+		events, err := eventStore.WithTx(tx).CreateEvents(context.Background(), reqs...)
+		require.NoError(t, err)
+		require.Len(t, events, len(reqs))
+
+		require.NoError(t, tx.Commit())
+
+		for i, event := range events {
+			require.Equal(t, reqs[i].Topic, event.Topic().String())
+			require.Equal(t, reqs[i].Key, event.Key())
+
+			if i > 0 {
+				require.Greater(t, event.Sequence(), events[i-1].Sequence())
+			}
+		}
+
+		require.Equal(t, []byte("test"), events[2].Payload())
+	})
+
+	t.Run("read events within the transaction", func(t *testing.T) {
+		tx, err := conn.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		txStore := eventStore.WithTx(tx)
+
+		event, err := txStore.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		events, err := txStore.NextEvents(context.Background(), event.Sequence()-1, 10, 0)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+
+		// The transaction's connection must be free for the next statement.
+		_, err = txStore.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		require.NoError(t, tx.Commit())
+	})
+}
+
+func TestPostgresEventStore_CommitOrderedSequences(t *testing.T) {
+	conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+
+	eventStore := flux.NewPostgresEventStore(conn, "events", flux.WithCommitOrderedSequences())
+
+	fluxtest.RunEventStoreSuite(t, func(t *testing.T) flux.EventStore {
+		conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+		require.NoError(t, err)
+
+		return flux.NewPostgresEventStore(conn, "events", flux.WithCommitOrderedSequences())
+	})
+
+	t.Run("writers wait for earlier transactions to commit", func(t *testing.T) {
+		txA, err := conn.BeginTx(context.Background(), nil)
+		require.NoError(t, err)
+
+		eventA, err := eventStore.WithTx(txA).CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+		require.NoError(t, err)
+
+		created := make(chan flux.Event, 1)
+		go func() {
+			eventB, err := eventStore.CreateEvent(context.Background(), uuid.NewString(), uuid.NewString())
+			if err != nil {
+				t.Errorf("create event: %v", err)
+			}
+
+			created <- eventB
+		}()
+
+		select {
+		case <-created:
+			t.Fatal("event was created while an earlier transaction was still open")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		require.NoError(t, txA.Commit())
+
+		eventB := <-created
+		require.NotNil(t, eventB)
+		require.Greater(t, eventB.Sequence(), eventA.Sequence())
+	})
+}
+
+func TestPostgresDeadLetterStore(t *testing.T) {
+	conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+
+	testDeadLetterStore(t, flux.NewPostgresDeadLetterStore(conn, "dead_letters"))
+}
+
+func testDeadLetterStore(t *testing.T, deadLetterStore flux.DeadLetterStore) {
+	ctx := context.Background()
+
+	events, err := flux.NewMemoryEventStore().CreateEvents(ctx,
+		flux.CreateEventRequest{Topic: "topic", Key: "first", Options: []flux.EventOption{
+			flux.WithPayload("text/plain", []byte("payload")),
+		}},
+		flux.CreateEventRequest{Topic: "topic", Key: "second"},
+		flux.CreateEventRequest{Topic: "topic", Key: "third"},
+	)
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+
+	// Store the letters out of order, to check that they are listed by sequence.
+	for _, i := range []int{2, 0, 1} {
+		err := deadLetterStore.DeadLetter(ctx, &flux.DeadLetter{
+			Event:          events[i],
+			Cause:          "first failure",
+			Attempts:       1,
+			DeadLetteredAt: now,
+		})
+		require.NoError(t, err)
+	}
+
+	// Dead-lettering an event again replaces its dead letter.
+	err = deadLetterStore.DeadLetter(ctx, &flux.DeadLetter{
+		Event:          events[0],
+		Cause:          "second failure",
+		Attempts:       2,
+		DeadLetteredAt: now,
+	})
+	require.NoError(t, err)
+
+	letters, err := deadLetterStore.ListDeadLetters(ctx, 2)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+
+	for i, letter := range letters {
+		require.Equal(t, events[i].ID(), letter.ID())
+		require.Equal(t, events[i].Topic(), letter.Topic())
+		require.Equal(t, events[i].Sequence(), letter.Sequence())
+		require.Equal(t, events[i].Key(), letter.Key())
+		require.Equal(t, events[i].Timestamp(), letter.Timestamp())
+		require.Equal(t, events[i].Payload(), letter.Payload())
+		require.Equal(t, events[i].ContentType(), letter.ContentType())
+		require.Equal(t, now, letter.DeadLetteredAt)
+	}
+
+	require.Equal(t, "second failure", letters[0].Cause)
+	require.Equal(t, 2, letters[0].Attempts)
+	require.Equal(t, "first failure", letters[1].Cause)
+
+	require.NoError(t, deadLetterStore.DeleteDeadLetter(ctx, events[0].ID()))
+	require.ErrorIs(t, deadLetterStore.DeleteDeadLetter(ctx, events[0].ID()), flux.ErrDeadLetterNotFound)
+
+	letters, err = deadLetterStore.ListDeadLetters(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 2)
+	require.Equal(t, events[1].ID(), letters[0].ID())
+}
+
+func TestPostgresLeaseStore(t *testing.T) {
+	conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+
+	testLeaseStore(t, flux.NewPostgresLeaseStore(conn, "leases"))
+}
+
+func TestPostgresEventStore_Notifications(t *testing.T) {
+	conn, err := sqlkit.ConnectForTesting(t, sqlkit.Postgres, pgMigrations)
+	require.NoError(t, err)
+	require.NotNil(t, conn)
+
+	eventStore := flux.NewPostgresEventStore(conn, "events", flux.WithNotifications("events"))
+	t.Cleanup(func() { require.NoError(t, eventStore.Close()) })
+
+	ctx := context.Background()
+
+	waitc := make(chan error, 1)
+	wait := func(from uint) {
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+			defer cancel()
+
+			waitc <- eventStore.Wait(ctx, from, 0)
+		}()
+	}
+
+	// Wait blocks until an event is created.
+	wait(0)
+	time.Sleep(100 * time.Millisecond)
+	require.Empty(t, waitc)
+
+	e, err := eventStore.CreateEvent(ctx, uuid.NewString(), uuid.NewString())
+	require.NoError(t, err)
+	require.NoError(t, <-waitc)
+
+	// Wait returns immediately if there are already events after the given sequence.
+	wait(e.Sequence() - 1)
+	require.NoError(t, <-waitc)
+
+	// Events created in a transaction are only notified once it commits.
+	wait(e.Sequence())
+
+	tx, err := conn.BeginTx(ctx, nil)
+	require.NoError(t, err)
+
+	_, err = eventStore.WithTx(tx).CreateEvent(ctx, uuid.NewString(), uuid.NewString())
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	require.Empty(t, waitc)
+
+	require.NoError(t, tx.Commit())
+	require.NoError(t, <-waitc)
+
+	// Events within the stream lag cannot be read yet, so Wait blocks until they leave it.
+	head, err := eventStore.Head(ctx)
+	require.NoError(t, err)
+
+	lagged, err := eventStore.CreateEvent(ctx, uuid.NewString(), uuid.NewString())
+	require.NoError(t, err)
+
+	const lag = 500 * time.Millisecond
+
+	require.NoError(t, eventStore.Wait(ctx, head.Sequence(), lag))
+	require.GreaterOrEqual(t, time.Since(lagged.Timestamp()), lag)
+
+	events, err := eventStore.NextEvents(ctx, head.Sequence(), 1, lag)
+	require.NoError(t, err)
+	require.Equal(t, lagged.ID(), events[0].ID())
 }
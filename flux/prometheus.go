@@ -0,0 +1,209 @@
+package flux
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Compile-time assertion that PrometheusObserver implements the RelayObserver interface.
+var _ RelayObserver = (*PrometheusObserver)(nil)
+
+// NewPrometheusObserver returns a RelayObserver which records metrics about a relay, and registers them with the
+// given registerer.
+//
+// To observe several relays with the same registerer, give each a distinct set of constant labels, such as the name of
+// the relay, using WithPrometheusLabels.
+func NewPrometheusObserver(reg prometheus.Registerer, opts ...PrometheusObserverOption) (*PrometheusObserver, error) {
+	config := DefaultPrometheusObserverConfig
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	o := &PrometheusObserver{
+		dispatched: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "events_dispatched_total",
+			Help:        "The number of events dispatched by the relay.",
+			ConstLabels: config.Labels,
+		}, []string{"topic"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "dispatch_failures_total",
+			Help:        "The number of failed attempts to dispatch an event.",
+			ConstLabels: config.Labels,
+		}, []string{"topic"}),
+		filtered: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "events_filtered_total",
+			Help:        "The number of events skipped because they did not match the stream's filters.",
+			ConstLabels: config.Labels,
+		}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "dispatch_duration_seconds",
+			Help:        "The time taken to dispatch an event, whether or not it succeeded.",
+			ConstLabels: config.Labels,
+			Buckets:     config.Buckets,
+		}, []string{"topic"}),
+		gaps: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "gaps_detected_total",
+			Help:        "The number of times the relay detected a gap in the sequences of the event stream.",
+			ConstLabels: config.Labels,
+		}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "retries_total",
+			Help:        "The number of times the relay failed and scheduled a retry.",
+			ConstLabels: config.Labels,
+		}),
+		position: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "position",
+			Help:        "The sequence of the most recent event processed by the relay.",
+			ConstLabels: config.Labels,
+		}),
+		lagSequences: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "lag_sequences",
+			Help:        "The number of sequences between the relay's position and the most recent event.",
+			ConstLabels: config.Labels,
+		}),
+		lagSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace:   config.Namespace,
+			Subsystem:   "relay",
+			Name:        "lag_seconds",
+			Help:        "The age of the event at the relay's position, or zero if the relay has caught up.",
+			ConstLabels: config.Labels,
+		}),
+	}
+
+	for _, c := range o.collectors() {
+		if err := reg.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register collector: %w", err)
+		}
+	}
+
+	return o, nil
+}
+
+// PrometheusObserver is a RelayObserver which records metrics using the Prometheus client.
+type PrometheusObserver struct {
+	dispatched   *prometheus.CounterVec
+	failed       *prometheus.CounterVec
+	filtered     prometheus.Counter
+	latency      *prometheus.HistogramVec
+	gaps         prometheus.Counter
+	retries      prometheus.Counter
+	position     prometheus.Gauge
+	lagSequences prometheus.Gauge
+	lagSeconds   prometheus.Gauge
+}
+
+type PrometheusObserverConfig struct {
+	// The namespace of the metrics' names.
+	Namespace string
+
+	// Constant labels which are added to every metric.
+	Labels prometheus.Labels
+
+	// The buckets of the dispatch latency histogram, in seconds.
+	Buckets []float64
+}
+
+var DefaultPrometheusObserverConfig = PrometheusObserverConfig{
+	Namespace: "flux",
+	Buckets:   prometheus.DefBuckets,
+}
+
+// PrometheusObserverOption is an interface that allows for functional options to be applied to a
+// PrometheusObserverConfig.
+type PrometheusObserverOption interface {
+	Apply(*PrometheusObserverConfig)
+}
+
+// PrometheusObserverOptionFunc is a function type that implements the PrometheusObserverOption interface.
+type PrometheusObserverOptionFunc func(*PrometheusObserverConfig)
+
+// Apply applies the function to the observer.
+func (f PrometheusObserverOptionFunc) Apply(config *PrometheusObserverConfig) {
+	f(config)
+}
+
+// WithPrometheusNamespace sets the namespace of the metrics' names.
+func WithPrometheusNamespace(namespace string) PrometheusObserverOption {
+	return PrometheusObserverOptionFunc(func(config *PrometheusObserverConfig) {
+		config.Namespace = namespace
+	})
+}
+
+// WithPrometheusLabels sets constant labels which are added to every metric.
+func WithPrometheusLabels(labels prometheus.Labels) PrometheusObserverOption {
+	return PrometheusObserverOptionFunc(func(config *PrometheusObserverConfig) {
+		config.Labels = labels
+	})
+}
+
+// WithPrometheusBuckets sets the buckets of the dispatch latency histogram, in seconds.
+func WithPrometheusBuckets(buckets []float64) PrometheusObserverOption {
+	return PrometheusObserverOptionFunc(func(config *PrometheusObserverConfig) {
+		config.Buckets = buckets
+	})
+}
+
+func (o *PrometheusObserver) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		o.dispatched, o.failed, o.filtered, o.latency, o.gaps, o.retries, o.position, o.lagSequences, o.lagSeconds,
+	}
+}
+
+func (o *PrometheusObserver) BatchFetched(ctx context.Context, from uint, count int) {}
+
+func (o *PrometheusObserver) EventDispatched(ctx context.Context, e Event, latency time.Duration) {
+	o.dispatched.WithLabelValues(string(e.Topic())).Inc()
+	o.latency.WithLabelValues(string(e.Topic())).Observe(latency.Seconds())
+}
+
+func (o *PrometheusObserver) DispatchFailed(ctx context.Context, e Event, latency time.Duration, err error) {
+	o.failed.WithLabelValues(string(e.Topic())).Inc()
+	o.latency.WithLabelValues(string(e.Topic())).Observe(latency.Seconds())
+}
+
+func (o *PrometheusObserver) EventFiltered(ctx context.Context, e Event) {
+	o.filtered.Inc()
+}
+
+func (o *PrometheusObserver) GapDetected(ctx context.Context, from, to uint) {
+	o.gaps.Inc()
+}
+
+func (o *PrometheusObserver) RetryScheduled(ctx context.Context, err error, next time.Duration) {
+	o.retries.Inc()
+}
+
+func (o *PrometheusObserver) PositionAdvanced(ctx context.Context, position uint, lag RelayLag) {
+	o.position.Set(float64(position))
+	o.lagSequences.Set(float64(lag.Sequences))
+	o.lagSeconds.Set(lag.Age.Seconds())
+}
+
+func (o *PrometheusObserver) Shutdown(ctx context.Context) {}
+
+func (o *PrometheusObserver) WaitFailed(ctx context.Context, err error) {}
+
+func (o *PrometheusObserver) LeaseAcquired(ctx context.Context, name string) {}
+
+func (o *PrometheusObserver) LeaseLost(ctx context.Context, name string, err error) {}
+
+func (o *PrometheusObserver) LeaseError(ctx context.Context, name string, err error) {}
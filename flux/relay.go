@@ -2,11 +2,14 @@ package flux
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Relay is a message broker that reads events from an event store and passes them to a dispatcher.
@@ -14,10 +17,16 @@ type Relay struct {
 	dispatcher Dispatcher
 	events     EventReader
 
-	config *RelayConfig
+	config   *RelayConfig
+	observer RelayObserver
+	tracer   trace.Tracer
 
+	// The lifecycle of the current run, guarded by mu. The shutdown channel is closed to ask the run to stop, and the
+	// done channel is closed once it has stopped. Both are replaced every time the relay is started.
+	mu       sync.Mutex
 	running  bool
 	shutdown chan struct{}
+	done     chan struct{}
 }
 
 type RelayConfig struct {
@@ -26,13 +35,85 @@ type RelayConfig struct {
 
 	// The size of the buffer used to store events before they are dispatched.
 	BufferSize uint
+
+	// Notified of the relay's activity. If nil, the relay logs its activity using slog.Default(), but does not query
+	// the event store to measure its lag.
+	Observer RelayObserver
+
+	// Used to start a span for every event that is dispatched, as a child of the span in which the event was created.
+	// If nil, the global tracer provider is used.
+	TracerProvider trace.TracerProvider
+
+	// How long the relay waits for new events once it has dispatched every event in the event store, before checking
+	// again. If the event store implements EventWaiter, the relay checks as soon as it is told of new events.
+	PollInterval time.Duration
+
+	// Determines how the relay handles gaps in the sequences of the event stream.
+	GapPolicy GapPolicy
+
+	// When using GapPolicySkip, the amount of time to wait for a gap to be filled before skipping it.
+	GapTimeout time.Duration
+
+	// If set, called with the range of sequences that were skipped when using GapPolicySkip.
+	OnGapSkipped func(from, to uint)
+
+	// If set, events which fail to dispatch MaxDispatchAttempts times are handed to the sink, after which the relay
+	// moves on to the next event. Otherwise, the relay retries a failing event indefinitely.
+	DeadLetterSink DeadLetterSink
+
+	// The number of times the relay attempts to dispatch an event before handing it to the DeadLetterSink.
+	MaxDispatchAttempts int
+
+	// If greater than one, events are dispatched concurrently in this many lanes, with events assigned to lanes by
+	// their keys. See WithPartitions.
+	Partitions uint
+
+	// If set, the relay only streams events while it holds the lease named LeaseName, so that only one of several
+	// relays sharing the lease is active at a time. The lease expires if it is not renewed within LeaseTTL.
+	Leases    LeaseStore
+	LeaseName string
+	LeaseTTL  time.Duration
+
+	// If set, the relay keeps track of its position using the cursor named CursorName, and resumes from it when it is
+	// restarted. The cursor is created at the StreamRequest's StartSequence if it does not exist.
+	Cursors    CursorStore
+	CursorName string
+
+	// If set, the cursor is updated once this many events have been streamed since the previous update.
+	CheckpointEvents uint
+
+	// If set, the cursor is updated once this much time has passed since the previous update.
+	CheckpointInterval time.Duration
 }
 
 var DefaultRelayConfig = RelayConfig{
-	BackOff:    backoff.NewConstantBackOff(5 * time.Second),
-	BufferSize: 5,
+	BackOff:      backoff.NewConstantBackOff(5 * time.Second),
+	BufferSize:   5,
+	PollInterval: time.Second,
 }
 
+// ErrRelayRunning is an error that is returned when a relay is started while it is already running.
+var ErrRelayRunning = errors.New("relay is already running")
+
+// ErrGapDetected is an error that is returned when the relay encounters a gap in the sequences of the event stream.
+var ErrGapDetected = errors.New("gap detected")
+
+// GapPolicy determines how a Relay handles gaps in the sequences of the event stream.
+//
+// Gaps occur when a sequence is allocated to an event which is never committed, such as when a transaction is rolled
+// back, or when events are committed out of order.
+type GapPolicy int
+
+const (
+	// GapPolicyStrict fails the stream whenever a gap is detected, and retries until the gap is filled. It is suitable
+	// for event stores which guarantee dense sequences.
+	GapPolicyStrict GapPolicy = iota
+
+	// GapPolicySkip waits for a gap to be filled for up to the relay's GapTimeout, after which the missing sequences
+	// are skipped.
+	GapPolicySkip
+)
+
 type StreamRequest struct {
 	// The sequence to start streaming events from. If zero, the relay will start streaming from the lowest sequence in
 	// the event store.
@@ -53,14 +134,24 @@ func NewRelay(dispatcher Dispatcher, events EventReader, opts ...RelayOption) *R
 		dispatcher: dispatcher,
 		events:     events,
 		config:     &defaultConfig,
-		running:    false,
-		shutdown:   make(chan struct{}, 1),
 	}
 
 	for _, opt := range opts {
 		opt.Apply(r.config)
 	}
 
+	r.observer = r.config.Observer
+	if r.observer == nil {
+		r.observer = NewSlogObserver(nil)
+	}
+
+	provider := r.config.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+
+	r.tracer = provider.Tracer(TracerName)
+
 	return &r
 }
 
@@ -86,6 +177,108 @@ func WithBufferSize(bufferSize uint) RelayOption {
 	})
 }
 
+// WithObserver sets the observers which are notified of the relay's activity, in place of logging it using
+// slog.Default().
+func WithObserver(observers ...RelayObserver) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		if len(observers) == 1 {
+			config.Observer = observers[0]
+			return
+		}
+
+		config.Observer = multiObserver(observers)
+	})
+}
+
+// WithTracerProvider sets the tracer provider used to trace the events the relay dispatches, in place of the global
+// tracer provider.
+func WithTracerProvider(provider trace.TracerProvider) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.TracerProvider = provider
+	})
+}
+
+// WithPollInterval sets how long the relay waits for new events before checking again, when the event store has no
+// more events.
+func WithPollInterval(interval time.Duration) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		if interval > 0 {
+			config.PollInterval = interval
+		}
+	})
+}
+
+// WithGapPolicy sets the gap policy of the relay, and the amount of time to wait for a gap to be filled before it is
+// skipped when using GapPolicySkip.
+func WithGapPolicy(policy GapPolicy, timeout time.Duration) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.GapPolicy = policy
+		config.GapTimeout = timeout
+	})
+}
+
+// WithGapSkippedFunc sets a function which is called with the range of sequences that were skipped by the relay.
+func WithGapSkippedFunc(fn func(from, to uint)) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.OnGapSkipped = fn
+	})
+}
+
+// WithDeadLetterSink sets the sink which receives events that have failed to dispatch maxAttempts times, allowing the
+// relay to advance past them.
+func WithDeadLetterSink(sink DeadLetterSink, maxAttempts int) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.DeadLetterSink = sink
+		config.MaxDispatchAttempts = maxAttempts
+	})
+}
+
+// WithPartitions makes the relay dispatch events concurrently, in the given number of lanes. Events are assigned to
+// lanes by hashing their keys, so events with the same key are still dispatched in order, while events with different
+// keys may be dispatched out of order. The dispatcher must be safe for concurrent use.
+//
+// Lanes dispatch the events of one buffer at a time, so the buffer size limits the number of events in flight. The
+// relay's position only advances past an event once it, and every event before it, has been dispatched.
+func WithPartitions(n uint) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.Partitions = n
+	})
+}
+
+// WithLeaderElection makes the relay campaign for the named lease, and only stream events while it is the leader. The
+// leader renews its lease every third of the TTL, and steps down as soon as a renewal fails. If the leader dies, a
+// standby takes over within roughly 4/3 of the TTL. A TTL of zero uses DefaultLeaseTTL, and the relay fails to start
+// with ErrInvalidLeaseTTL if the TTL is shorter than MinLeaseTTL.
+//
+// Relays which share a lease should also share a cursor (see WithCursor), so that a new leader resumes where the
+// previous one left off.
+func WithLeaderElection(leases LeaseStore, name string, ttl time.Duration) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.Leases = leases
+		config.LeaseName = name
+		config.LeaseTTL = ttl
+	})
+}
+
+// WithCursor binds the relay to the named cursor, which it resumes from and updates as it streams events. By default,
+// the cursor is updated after every batch of events.
+func WithCursor(cursors CursorStore, name string) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.Cursors = cursors
+		config.CursorName = name
+	})
+}
+
+// WithCheckpointFrequency sets how often the relay updates its cursor: once the given number of events have been
+// streamed, or the given amount of time has passed, since the previous update. Updating the cursor less often reduces
+// the write load on the cursor store, at the cost of dispatching more events again after a restart.
+func WithCheckpointFrequency(events uint, interval time.Duration) RelayOption {
+	return RelayOptionFunc(func(config *RelayConfig) {
+		config.CheckpointEvents = events
+		config.CheckpointInterval = interval
+	})
+}
+
 // WithBackOff sets the backoff strategy of the relay.
 func WithBackOff(backOff backoff.BackOff) RelayOption {
 	return RelayOptionFunc(func(config *RelayConfig) {
@@ -93,68 +286,272 @@ func WithBackOff(backOff backoff.BackOff) RelayOption {
 	})
 }
 
+// Start streams events to the dispatcher until the relay is shut down, the context is cancelled, or the relay fails
+// permanently. It returns ErrRelayRunning if the relay is already running. A relay which has stopped may be started
+// again, in which case it resumes from its cursor, if it has one, or otherwise from the request's StartSequence.
 func (r *Relay) Start(ctx context.Context, req StreamRequest) error {
+	r.mu.Lock()
 	if r.running {
-		return fmt.Errorf("relay is already running")
+		r.mu.Unlock()
+		return ErrRelayRunning
 	}
 
 	r.running = true
+	r.shutdown = make(chan struct{})
+	r.done = make(chan struct{})
+	done := r.done
+	r.mu.Unlock()
 
-	fn := func() error {
-		return r.stream(ctx, req)
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+
+		close(done)
+	}()
+
+	// The stream is shared between retries so that the relay resumes from its last position.
+	s := &stream{
+		buffer:      make([]Event, 0),
+		bufferSize:  r.config.BufferSize,
+		filters:     req.Filters,
+		lag:         req.StreamLag,
+		position:    req.StartSequence,
+		gapPolicy:   r.config.GapPolicy,
+		gapTimeout:  r.config.GapTimeout,
+		onGap:       r.config.OnGapSkipped,
+		deadLetter:  r.config.DeadLetterSink,
+		maxAttempts: r.config.MaxDispatchAttempts,
+		partitions:  r.config.Partitions,
+		observer:    r.observer,
+		tracer:      r.tracer,
 	}
 
 	notify := func(err error, next time.Duration) {
-		log.Printf("error: %v, next: %v", err, next)
+		r.observer.RetryScheduled(ctx, err, next)
 	}
 
-	return backoff.RetryNotify(fn, backoff.WithContext(r.config.BackOff, ctx), notify)
-}
+	run := func(ctx context.Context) error {
+		// The checkpoint is created on the first attempt that manages to load the relay's cursor. The cursor is loaded
+		// again on every run, as another relay may have moved it while this one was not the leader.
+		var cp *checkpoint
 
-func (r *Relay) stream(ctx context.Context, req StreamRequest) error {
-	if !r.running {
-		return backoff.Permanent(fmt.Errorf("relay is not running"))
+		fn := func() error {
+			if r.config.Cursors != nil && cp == nil {
+				cursor, err := lookupOrCreateCursor(ctx, r.config.Cursors, r.config.CursorName, req.StartSequence)
+				if err != nil {
+					return err
+				}
+
+				s.position = cursor.Sequence()
+				cp = newCheckpoint(cursor, r.config.CheckpointEvents, r.config.CheckpointInterval)
+			}
+
+			return r.stream(ctx, s, cp)
+		}
+
+		// Stop retrying as soon as the relay is shut down, rather than once the next attempt is due.
+		retryCtx, cancel := r.untilShutdown(ctx)
+		defer cancel()
+
+		return backoff.RetryNotify(fn, backoff.WithContext(r.config.BackOff, retryCtx), notify)
 	}
 
-	s := &stream{
-		buffer:     make([]Event, 0),
-		bufferSize: r.config.BufferSize,
-		filters:    req.Filters,
-		lag:        req.StreamLag,
-		position:   req.StartSequence,
+	if r.config.Leases != nil {
+		return r.lead(ctx, run)
 	}
 
-	// Ensure all events are dispatched before returning.
-	defer s.flush(ctx, r.dispatcher)
+	return run(ctx)
+}
+
+func (r *Relay) stream(ctx context.Context, s *stream, cp *checkpoint) error {
+	// Ensure the cursor reflects every dispatched event before returning, even if the context has been cancelled.
+	if cp != nil {
+		defer func() {
+			_ = cp.commit(context.WithoutCancel(ctx), r.config.Cursors, s.position)
+		}()
+	}
+
+	// Discard any events left over from a failed attempt, they are fetched again from the current position.
+	s.buffer = s.buffer[:0]
 
 	// Start main loop.
 	for {
 		select {
 		case <-r.shutdown:
+			// Ensure all events are dispatched before returning.
+			if _, err := gapWait(s.flush(ctx, r.dispatcher)); err != nil {
+				return err
+			}
+
+			r.observer.Shutdown(ctx)
+
 			return nil
+		case <-ctx.Done():
+			return ctx.Err()
 		default:
 			if err := s.spool(ctx, r.events); err != nil {
-				return fmt.Errorf("failed to spool events: %w", err)
+				if !errors.Is(err, ErrEventNotFound) && !errors.Is(err, ErrNoMoreEvents) {
+					return fmt.Errorf("failed to spool events: %w", err)
+				}
+
+				// The relay has caught up with the event store, so record its position while it waits for new events.
+				if cp != nil {
+					if err := cp.commit(ctx, r.config.Cursors, s.position); err != nil {
+						return err
+					}
+				}
+
+				r.idle(ctx, s.position, s.lag)
+				continue
 			}
 
-			if err := s.flush(ctx, r.dispatcher); err != nil {
+			// A partial batch means the relay has caught up with the event store.
+			caughtUp := uint(len(s.buffer)) < s.bufferSize
+			from := s.position
+
+			// Waiting for a gap to be filled is not a failure, so the relay waits without backing off.
+			wait, err := gapWait(s.flush(ctx, r.dispatcher))
+			if err != nil {
 				return fmt.Errorf("failed to flush events: %w", err)
 			}
 
+			if s.position != from {
+				r.observeLag(ctx, s, caughtUp)
+			}
+
+			if cp != nil && (cp.everyBatch() || cp.due(s.position)) {
+				if err := cp.commit(ctx, r.config.Cursors, s.position); err != nil {
+					return err
+				}
+			}
+
+			if wait > 0 {
+				s.buffer = s.buffer[:0]
+				sleep(ctx, min(wait, r.config.PollInterval), r.shutdown)
+			}
+
 			// Reset the backoff strategy if we successfully processed an event batch.
 			r.config.BackOff.Reset()
 		}
 	}
 }
 
-// Shutdown gracefully shuts down the relay, flushing any events that are still in the buffer.
-func (r *Relay) Shutdown() {
+// observeLag reports the relay's position, and how far it lags behind the event store. The event store is only queried
+// for the lag when an observer is configured, since the default observer does not need it, and the relay has not
+// caught up with it.
+func (r *Relay) observeLag(ctx context.Context, s *stream, caughtUp bool) {
+	var lag RelayLag
+
+	if r.config.Observer != nil && !caughtUp {
+		if head, err := r.events.Head(ctx); err == nil && head.Sequence() > s.position {
+			lag.Sequences = head.Sequence() - s.position
+			lag.Age = time.Since(s.positionTimestamp)
+		}
+	}
+
+	r.observer.PositionAdvanced(ctx, s.position, lag)
+}
+
+// idle blocks until there may be new events after the given position, which are older than the stream lag. It returns
+// early if the relay is shut down.
+func (r *Relay) idle(ctx context.Context, from uint, lag time.Duration) {
+	waitForEvents(ctx, r.events, from, lag, r.config.PollInterval, r.shutdown, r.observer)
+}
+
+// waitForEvents blocks until there may be new events after the given position, which are older than the stream lag:
+// when the event store signals them, if it is an EventWaiter, or once the poll interval has passed. It returns early
+// if the shutdown channel is closed.
+func waitForEvents(
+	ctx context.Context,
+	events EventReader,
+	from uint,
+	lag time.Duration,
+	interval time.Duration,
+	shutdown <-chan struct{},
+	observer RelayObserver,
+) {
+	waitCtx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	notified := make(chan struct{})
+
+	if waiter, ok := events.(EventWaiter); ok {
+		go func() {
+			// If waiting fails, the reader falls back to polling. Waiting is expected to stop once the poll interval
+			// has passed, and may not be supported by the event store, so only other failures are reported.
+			err := waiter.Wait(waitCtx, from, lag)
+			if err == nil {
+				close(notified)
+			} else if waitCtx.Err() == nil && !errors.Is(err, ErrWaitNotSupported) {
+				observer.WaitFailed(ctx, err)
+			}
+		}()
+	}
+
+	select {
+	case <-waitCtx.Done():
+	case <-notified:
+	case <-shutdown:
+	}
+}
+
+// sleep blocks for the given duration, or until the context is cancelled or the shutdown channel is closed.
+func sleep(ctx context.Context, d time.Duration, shutdown <-chan struct{}) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	case <-shutdown:
+	}
+}
+
+// untilShutdown returns a copy of the context which is cancelled when the relay is shut down.
+func (r *Relay) untilShutdown(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	// The goroutine may outlive the run, so it must not read the channel from the relay once the relay is restarted.
+	shutdown := r.shutdown
+
+	go func() {
+		select {
+		case <-shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, cancel
+}
+
+// Shutdown gracefully shuts down the relay, and blocks until it has flushed the events that are still in its buffer,
+// or the context expires, in which case the context's error is returned. The relay keeps shutting down in the
+// background if the context expires. Shutting down a relay which is not running does nothing.
+func (r *Relay) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
 	if !r.running {
-		return
+		r.mu.Unlock()
+		return nil
 	}
 
-	r.running = false
-	close(r.shutdown)
+	select {
+	case <-r.shutdown:
+		// The relay is already shutting down.
+	default:
+		close(r.shutdown)
+	}
+
+	done := r.done
+	r.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 type stream struct {
@@ -163,29 +560,182 @@ type stream struct {
 	filters    []EventFilter
 	lag        time.Duration
 	position   uint
+
+	gapPolicy  GapPolicy
+	gapTimeout time.Duration
+	onGap      func(from, to uint)
+
+	// The time at which the relay first detected a gap at the current position, if any.
+	gapDetectedAt time.Time
+
+	deadLetter  DeadLetterSink
+	maxAttempts int
+
+	// The number of failed attempts to dispatch each event which has not yet been dispatched. It is guarded by mu, as
+	// it may be updated by several lanes at once.
+	mu       sync.Mutex
+	attempts map[string]int
+
+	// If greater than one, events are dispatched concurrently in this many lanes. See flushPartitioned.
+	partitions uint
+
+	// The sequences of events beyond the current position which have already been dispatched by a lane.
+	completed map[uint]bool
+
+	// The timestamp of the event at the current position.
+	positionTimestamp time.Time
+
+	observer RelayObserver
+
+	// If set, every dispatch is traced in a span of its own.
+	tracer trace.Tracer
 }
 
 func (s *stream) maybeDispatchEvent(ctx context.Context, e Event, d Dispatcher) error {
 	// Detect gaps in the event stream. Note, this must be run before filtering.
 	if e.Sequence() != s.position+1 {
-		return fmt.Errorf("gap detected between event %d and %d", s.position, e.Sequence())
+		if err := s.handleGap(ctx, s.position, e); err != nil {
+			return err
+		}
 	}
 
-	// Apply filters.
-	shouldDispatch := true
+	if s.shouldDispatch(e) {
+		if err := s.dispatch(ctx, e, d); err != nil {
+			return err
+		}
+	} else {
+		s.observer.EventFiltered(ctx, e)
+	}
+
+	s.position = e.Sequence()
+	s.positionTimestamp = e.Timestamp()
+	s.gapDetectedAt = time.Time{}
+
+	return nil
+}
+
+// shouldDispatch returns true if the event matches all of the stream's filters.
+func (s *stream) shouldDispatch(e Event) bool {
 	for _, filter := range s.filters {
 		if !filter.Apply(e) {
-			shouldDispatch = false
+			return false
 		}
 	}
 
-	if shouldDispatch {
-		if err := d.Dispatch(ctx, e); err != nil {
-			return fmt.Errorf("failed to dispatch event %s: %w", e.ID(), err)
-		}
+	return true
+}
+
+// dispatch dispatches the event, handing it to the dead-letter sink once it has failed too many times. It is safe to
+// call from several lanes at once.
+func (s *stream) dispatch(ctx context.Context, e Event, d Dispatcher) error {
+	var span trace.Span
+	if s.tracer != nil {
+		ctx, span = startDispatchSpan(ctx, s.tracer, e)
 	}
 
-	s.position = e.Sequence()
+	start := time.Now()
+	err := d.Dispatch(ctx, e)
+	latency := time.Since(start)
+
+	if span != nil {
+		endDispatchSpan(span, err)
+	}
+
+	if err != nil {
+		s.observer.DispatchFailed(ctx, e, latency, err)
+	} else {
+		s.observer.EventDispatched(ctx, e, latency)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		return s.handleDispatchFailure(ctx, e, err)
+	}
+
+	delete(s.attempts, e.ID())
+
+	return nil
+}
+
+// gapPendingError is returned by the stream while it waits for a gap to be filled, when using GapPolicySkip.
+type gapPendingError struct {
+	err error
+
+	// The remaining time until the gap is skipped.
+	wait time.Duration
+}
+
+func (e *gapPendingError) Error() string { return e.err.Error() }
+func (e *gapPendingError) Unwrap() error { return e.err }
+
+// gapWait returns the time to wait before checking again whether a gap has been filled, if err is a gapPendingError.
+// Any other error is returned as is.
+func gapWait(err error) (time.Duration, error) {
+	var pending *gapPendingError
+	if errors.As(err, &pending) {
+		return pending.wait, nil
+	}
+
+	return 0, err
+}
+
+// handleGap returns nil if the event after a gap may be dispatched, a gapPendingError if the stream should wait for
+// the gap to be filled, or any other error if the stream has failed. The gap starts after the from sequence.
+func (s *stream) handleGap(ctx context.Context, from uint, e Event) error {
+	err := fmt.Errorf("%w between event %d and %d", ErrGapDetected, from, e.Sequence())
+
+	// Events at, or before, the current position can never be dispatched in order.
+	if s.gapPolicy != GapPolicySkip || e.Sequence() <= from {
+		s.observer.GapDetected(ctx, from, e.Sequence())
+		return err
+	}
+
+	// The gap is only reported once, rather than every time the stream checks whether it has been filled.
+	if s.gapDetectedAt.IsZero() {
+		s.gapDetectedAt = time.Now()
+		s.observer.GapDetected(ctx, from, e.Sequence())
+	}
+
+	if wait := s.gapTimeout - time.Since(s.gapDetectedAt); wait > 0 {
+		return &gapPendingError{err: err, wait: wait}
+	}
+
+	if s.onGap != nil {
+		s.onGap(from+1, e.Sequence()-1)
+	}
+
+	return nil
+}
+
+// handleDispatchFailure returns nil if the event has been dead-lettered, or an error if it should be retried. It must
+// be called with mu held.
+func (s *stream) handleDispatchFailure(ctx context.Context, e Event, cause error) error {
+	if s.attempts == nil {
+		s.attempts = make(map[string]int)
+	}
+
+	s.attempts[e.ID()]++
+
+	err := fmt.Errorf("failed to dispatch event %s: %w", e.ID(), cause)
+
+	if s.deadLetter == nil || s.attempts[e.ID()] < s.maxAttempts {
+		return err
+	}
+
+	letter := &DeadLetter{
+		Event:          e,
+		Cause:          cause.Error(),
+		Attempts:       s.attempts[e.ID()],
+		DeadLetteredAt: time.Now().UTC(),
+	}
+
+	if err := s.deadLetter.DeadLetter(ctx, letter); err != nil {
+		return fmt.Errorf("failed to dead-letter event %s: %w", e.ID(), err)
+	}
+
+	delete(s.attempts, e.ID())
 
 	return nil
 }
@@ -196,12 +746,18 @@ func (s *stream) spool(ctx context.Context, events EventReader) error {
 		return fmt.Errorf("failed to fetch next events: %w", err)
 	}
 
+	s.observer.BatchFetched(ctx, s.position, len(el))
+
 	s.buffer = append(s.buffer, el...)
 
 	return nil
 }
 
 func (s *stream) flush(ctx context.Context, d Dispatcher) error {
+	if s.partitions > 1 {
+		return s.flushPartitioned(ctx, d)
+	}
+
 	for len(s.buffer) > 0 {
 		e := s.buffer[0]
 		s.buffer = s.buffer[1:]
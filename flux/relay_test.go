@@ -2,6 +2,11 @@ package flux_test
 
 import (
 	"context"
+	"errors"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -17,10 +22,16 @@ func TestRelay_Shutdown(t *testing.T) {
 
 	generator := NewEventGenerator(t)
 
+	var (
+		once    sync.Once
+		started = make(chan struct{})
+	)
+
 	dispatcher := mocks.NewMockDispatcher(ctrl)
 	dispatcher.
 		EXPECT().
 		Dispatch(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, flux.Event) { once.Do(func() { close(started) }) }).
 		Return(nil).
 		AnyTimes()
 
@@ -34,12 +45,16 @@ func TestRelay_Shutdown(t *testing.T) {
 		flux.WithBackOff(&backoff.ZeroBackOff{}),
 	)
 
+	errc := make(chan error, 1)
 	go func() {
-		err := relay.Start(context.Background(), req)
-		require.NoError(t, err)
+		errc <- relay.Start(context.Background(), req)
 	}()
 
-	relay.Shutdown()
+	// Shutting down a relay which has not been started yet does nothing, so wait for it to start.
+	<-started
+
+	require.NoError(t, relay.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
 }
 
 func TestRelay_StreamEvents(t *testing.T) {
@@ -90,6 +105,7 @@ func setupEventReader(
 	events []flux.Event,
 	config flux.RelayConfig,
 	req flux.StreamRequest,
+	onIdle func(),
 ) flux.EventReader {
 	t.Helper()
 
@@ -119,6 +135,7 @@ func setupEventReader(
 	eventReader.
 		EXPECT().
 		NextEvents(gomock.Any(), uint(eventCount), config.BufferSize, req.StreamLag).
+		Do(func(context.Context, uint, uint, time.Duration) { onIdle() }).
 		Return(nil, flux.ErrNoMoreEvents).
 		AnyTimes()
 
@@ -146,11 +163,18 @@ func setupDispatcher(t *testing.T, ctrl *gomock.Controller, events []flux.Event,
 	return dispatcher
 }
 
-func setupRelay(t *testing.T, ctrl *gomock.Controller, events []flux.Event, config flux.RelayConfig, req flux.StreamRequest) *flux.Relay {
+func setupRelay(
+	t *testing.T,
+	ctrl *gomock.Controller,
+	events []flux.Event,
+	config flux.RelayConfig,
+	req flux.StreamRequest,
+	onIdle func(),
+) *flux.Relay {
 	t.Helper()
 
 	dispatcher := setupDispatcher(t, ctrl, events, req)
-	eventReader := setupEventReader(t, ctrl, events, config, req)
+	eventReader := setupEventReader(t, ctrl, events, config, req, onIdle)
 
 	opts := []flux.RelayOption{
 		flux.WithBufferSize(config.BufferSize),
@@ -177,10 +201,581 @@ func testRelay_StreamEvents(t *testing.T, eventCount uint, relayOptions flux.Rel
 		events[i] = e
 	}
 
-	relay := setupRelay(t, ctrl, events, relayOptions, streamConfig)
+	// Running out of events is not an error, so the relay is shut down once it has streamed every event.
+	var (
+		once sync.Once
+		idle = make(chan struct{})
+	)
+
+	relay := setupRelay(t, ctrl, events, relayOptions, streamConfig, func() {
+		once.Do(func() { close(idle) })
+	})
+
+	go func() {
+		<-idle
+		_ = relay.Shutdown(context.Background())
+	}()
 
 	err := relay.Start(context.Background(), streamConfig)
-	require.ErrorIs(t, err, flux.ErrNoMoreEvents)
+	require.NoError(t, err)
+}
+
+func TestRelay_GapPolicy(t *testing.T) {
+	events := generateEvents(t, 5)
+
+	// Remove the third event to create a gap in the sequences.
+	withGap := append(append([]flux.Event{}, events[:2]...), events[3:]...)
+
+	type skipped struct {
+		from, to uint
+	}
+
+	setup := func(opts ...flux.RelayOption) (*flux.Relay, *staticEventReader, *[]flux.Event, *[]skipped) {
+		var (
+			dispatched []flux.Event
+			gaps       []skipped
+		)
+
+		dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			dispatched = append(dispatched, e)
+			return nil
+		})
+
+		opts = append(opts, flux.WithGapSkippedFunc(func(from, to uint) {
+			gaps = append(gaps, skipped{from, to})
+		}))
+
+		reader := &staticEventReader{events: withGap}
+
+		return flux.NewRelay(dispatcher, reader, opts...), reader, &dispatched, &gaps
+	}
+
+	t.Run("strict", func(t *testing.T) {
+		relay, _, dispatched, gaps := setup(flux.WithBackOff(&backoff.StopBackOff{}))
+
+		err := relay.Start(context.Background(), flux.StreamRequest{})
+		require.ErrorIs(t, err, flux.ErrGapDetected)
+		require.Equal(t, events[:2], *dispatched)
+		require.Empty(t, *gaps)
+	})
+
+	t.Run("skip immediately", func(t *testing.T) {
+		relay, reader, dispatched, gaps := setup(
+			flux.WithBackOff(&backoff.StopBackOff{}),
+			flux.WithGapPolicy(flux.GapPolicySkip, 0),
+		)
+
+		err := runRelay(t, relay, reader, flux.StreamRequest{})
+		require.NoError(t, err)
+		require.Equal(t, withGap, *dispatched)
+		require.Equal(t, []skipped{{3, 3}}, *gaps)
+	})
+
+	t.Run("skip after timeout", func(t *testing.T) {
+		const timeout = 50 * time.Millisecond
+
+		var observer recordingObserver
+
+		// Waiting for the gap is not a failure, so the relay does not need to retry.
+		relay, reader, dispatched, gaps := setup(
+			flux.WithBackOff(&backoff.StopBackOff{}),
+			flux.WithGapPolicy(flux.GapPolicySkip, timeout),
+			flux.WithPollInterval(5*time.Millisecond),
+			flux.WithObserver(&observer),
+		)
+
+		// The relay waits for the gap until the timeout has passed, and then runs until it has streamed every event.
+		start := time.Now()
+		err := runRelay(t, relay, reader, flux.StreamRequest{})
+		require.NoError(t, err)
+
+		require.Equal(t, withGap, *dispatched)
+		require.Equal(t, []skipped{{3, 3}}, *gaps)
+		require.GreaterOrEqual(t, time.Since(start), timeout)
+		require.Zero(t, observer.retries)
+		require.Equal(t, 1, observer.gaps)
+	})
+}
+
+func TestRelay_DeadLetters(t *testing.T) {
+	events := generateEvents(t, 5)
+	poison := events[2]
+
+	errPoison := errors.New("poison event")
+
+	var (
+		dispatched []flux.Event
+		attempts   int
+		healthy    bool
+	)
+
+	dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+		if e.ID() == poison.ID() && !healthy {
+			attempts++
+			return errPoison
+		}
+
+		dispatched = append(dispatched, e)
+		return nil
+	})
+
+	store := flux.NewMemoryDeadLetterStore()
+	reader := &staticEventReader{events: events}
+
+	relay := flux.NewRelay(
+		dispatcher,
+		reader,
+		flux.WithBackOff(backoff.WithMaxRetries(&backoff.ZeroBackOff{}, 5)),
+		flux.WithDeadLetterSink(store, 3),
+	)
+
+	// The relay moves past the poison event, and runs until it has streamed every event.
+	err := runRelay(t, relay, reader, flux.StreamRequest{})
+	require.NoError(t, err)
+
+	require.Equal(t, 3, attempts)
+	require.Equal(t, []flux.Event{events[0], events[1], events[3], events[4]}, dispatched)
+
+	letters, err := store.ListDeadLetters(context.Background(), 10)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, poison, letters[0].Event)
+	require.Equal(t, 3, letters[0].Attempts)
+	require.Contains(t, letters[0].Cause, errPoison.Error())
+
+	t.Run("replay", func(t *testing.T) {
+		healthy = false
+		require.ErrorIs(t, relay.ReplayDeadLetters(context.Background(), store, letters...), errPoison)
+
+		remaining, err := store.ListDeadLetters(context.Background(), 10)
+		require.NoError(t, err)
+		require.Len(t, remaining, 1)
+
+		healthy = true
+		require.NoError(t, relay.ReplayDeadLetters(context.Background(), store, letters...))
+		require.Equal(t, poison, dispatched[len(dispatched)-1])
+
+		remaining, err = store.ListDeadLetters(context.Background(), 10)
+		require.NoError(t, err)
+		require.Empty(t, remaining)
+	})
+
+	t.Run("dispatcher sink", func(t *testing.T) {
+		var received []*flux.DeadLetter
+		sink := flux.DispatchDeadLetters(flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			letter, ok := e.(*flux.DeadLetter)
+			require.True(t, ok)
+			received = append(received, letter)
+			return nil
+		}))
+
+		require.NoError(t, sink.DeadLetter(context.Background(), letters[0]))
+		require.Equal(t, letters, received)
+	})
+
+	// Transports carry the details of the dead letter along with the event.
+	requireDeadLetterEqual := func(t *testing.T, expected *flux.DeadLetter, actual flux.Event) {
+		t.Helper()
+
+		letter, ok := actual.(*flux.DeadLetter)
+		require.True(t, ok, "expected a dead letter, got %T", actual)
+		requireDecodedEventEqual(t, expected.Event, letter.Event)
+		require.Equal(t, expected.Cause, letter.Cause)
+		require.Equal(t, expected.Attempts, letter.Attempts)
+		require.True(t, expected.DeadLetteredAt.Equal(letter.DeadLetteredAt))
+	}
+
+	t.Run("webhook sink", func(t *testing.T) {
+		secret := []byte("test-secret")
+
+		received := make(chan flux.Event, 1)
+		receiver := flux.NewWebhookReceiver(flux.NewJSONCodec(), secret, func(ctx context.Context, e flux.Event) error {
+			received <- e
+			return nil
+		})
+
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		sink := flux.DispatchDeadLetters(flux.NewWebhookDispatcher(server.URL, flux.NewJSONCodec(), secret))
+		require.NoError(t, sink.DeadLetter(context.Background(), letters[0]))
+
+		requireDeadLetterEqual(t, letters[0], <-received)
+	})
+
+	t.Run("nats sink", func(t *testing.T) {
+		conn := setupNats(t)
+
+		sub, err := conn.SubscribeSync(poison.Topic().String())
+		require.NoError(t, err)
+
+		sink := flux.DispatchDeadLetters(flux.NewNatsDispatcher(conn, flux.NewJSONCodec()))
+		require.NoError(t, sink.DeadLetter(context.Background(), letters[0]))
+
+		msg, err := sub.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+
+		require.Equal(t, letters[0].Cause, msg.Header.Get(flux.DeadLetterHeaderCause))
+		require.Equal(t, strconv.Itoa(letters[0].Attempts), msg.Header.Get(flux.DeadLetterHeaderAttempts))
+		require.Equal(t, letters[0].DeadLetteredAt.Format(time.RFC3339Nano), msg.Header.Get(flux.DeadLetterHeaderTime))
+	})
+
+	t.Run("protobuf", func(t *testing.T) {
+		codec := flux.NewProtobufCodec()
+
+		data, err := codec.Encode(letters[0])
+		require.NoError(t, err)
+
+		e, err := codec.Decode(data)
+		require.NoError(t, err)
+
+		requireDeadLetterEqual(t, letters[0], e)
+	})
+}
+
+func TestRelay_Cursor(t *testing.T) {
+	const cursorName = "test-relay"
+
+	events := generateEvents(t, 10)
+
+	t.Run("resumes from the cursor", func(t *testing.T) {
+		cursors := flux.NewMemoryCursorStore()
+
+		var dispatched []flux.Event
+		dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			dispatched = append(dispatched, e)
+			return nil
+		})
+
+		run := func(req flux.StreamRequest) error {
+			reader := &staticEventReader{events: events}
+
+			relay := flux.NewRelay(
+				dispatcher,
+				reader,
+				flux.WithBackOff(&backoff.StopBackOff{}),
+				flux.WithCursor(cursors, cursorName),
+			)
+
+			return runRelay(t, relay, reader, req)
+		}
+
+		// The cursor is created at the start sequence.
+		require.NoError(t, run(flux.StreamRequest{StartSequence: 3}))
+		require.Equal(t, events[3:], dispatched)
+
+		cursor, err := cursors.LookupCursorByName(context.Background(), cursorName)
+		require.NoError(t, err)
+		require.Equal(t, uint(10), cursor.Sequence())
+
+		// A restarted relay resumes from the cursor, rather than the start sequence.
+		dispatched = nil
+
+		require.NoError(t, run(flux.StreamRequest{StartSequence: 3}))
+		require.Empty(t, dispatched)
+	})
+
+	t.Run("checkpoint frequency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+
+		cursor := &testCursor{id: "cursor-id", name: cursorName}
+
+		cursors := mocks.NewMockCursorStore(ctrl)
+		cursors.EXPECT().LookupCursorByName(gomock.Any(), cursorName).Return(cursor, nil)
+		gomock.InOrder(
+			cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(4)).Return(nil),
+			cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(8)).Return(nil),
+			// The final position is committed once the relay has caught up with the event store.
+			cursors.EXPECT().UpdateCursor(gomock.Any(), cursor.ID(), uint(10)).Return(nil),
+		)
+
+		reader := &staticEventReader{events: events}
+
+		relay := flux.NewRelay(
+			flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error { return nil }),
+			reader,
+			flux.WithBackOff(&backoff.StopBackOff{}),
+			flux.WithBufferSize(2),
+			flux.WithCursor(cursors, cursorName),
+			flux.WithCheckpointFrequency(4, 0),
+		)
+
+		require.NoError(t, runRelay(t, relay, reader, flux.StreamRequest{}))
+	})
+}
+
+// countingEventStore is a MemoryEventStore which counts the calls to NextEvents.
+type countingEventStore struct {
+	*flux.MemoryEventStore
+	calls atomic.Int64
+}
+
+func (store *countingEventStore) NextEvents(
+	ctx context.Context,
+	from, batchSize uint,
+	streamLag time.Duration,
+) ([]flux.Event, error) {
+	store.calls.Add(1)
+	return store.MemoryEventStore.NextEvents(ctx, from, batchSize, streamLag)
+}
+
+func TestRelay_Idle(t *testing.T) {
+	store := &countingEventStore{MemoryEventStore: flux.NewMemoryEventStore()}
+
+	dispatched := make(chan flux.Event, 10)
+	dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+		dispatched <- e
+		return nil
+	})
+
+	relay := flux.NewRelay(
+		dispatcher,
+		store,
+		flux.WithBackOff(&backoff.StopBackOff{}),
+		flux.WithPollInterval(time.Hour),
+	)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- relay.Start(context.Background(), flux.StreamRequest{})
+	}()
+
+	// An idle relay waits for new events, rather than failing or polling for them.
+	time.Sleep(50 * time.Millisecond)
+	require.Empty(t, errc)
+	require.Equal(t, int64(1), store.calls.Load())
+
+	// The relay is woken up as soon as an event is created.
+	e, err := store.CreateEvent(context.Background(), "topic", "key")
+	require.NoError(t, err)
+
+	select {
+	case got := <-dispatched:
+		require.Equal(t, e.ID(), got.ID())
+	case <-time.After(time.Second):
+		require.Fail(t, "event was not dispatched")
+	}
+
+	// An idle relay can be shut down without waiting for the poll interval.
+	require.NoError(t, relay.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+}
+
+func TestRelay_Idle_StreamLag(t *testing.T) {
+	store := &countingEventStore{MemoryEventStore: flux.NewMemoryEventStore()}
+
+	// The event stays within the stream lag for the duration of the test, so it is never read.
+	_, err := store.CreateEvent(context.Background(), "topic", "key")
+	require.NoError(t, err)
+
+	relay := flux.NewRelay(
+		flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			t.Error("event within the stream lag was dispatched")
+			return nil
+		}),
+		store,
+		flux.WithBackOff(&backoff.StopBackOff{}),
+		flux.WithPollInterval(time.Hour),
+	)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- relay.Start(context.Background(), flux.StreamRequest{StreamLag: time.Minute})
+	}()
+
+	// The relay waits for the event to leave the stream lag, rather than fetching it again and again.
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, int64(1), store.calls.Load())
+
+	require.NoError(t, relay.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+}
+
+// gatedDispatcher is a Dispatcher which blocks every dispatch until it is released, so that tests can control which
+// dispatches are in flight.
+type gatedDispatcher struct {
+	dispatching chan flux.Event
+	release     chan struct{}
+}
+
+func newGatedDispatcher() *gatedDispatcher {
+	return &gatedDispatcher{
+		dispatching: make(chan flux.Event, 100),
+		release:     make(chan struct{}, 100),
+	}
+}
+
+func (d *gatedDispatcher) Dispatch(ctx context.Context, e flux.Event) error {
+	d.dispatching <- e
+
+	select {
+	case <-d.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestRelay_Lifecycle(t *testing.T) {
+	setup := func(t *testing.T, opts ...flux.RelayOption) (*flux.Relay, *flux.MemoryEventStore, *gatedDispatcher) {
+		t.Helper()
+
+		store := flux.NewMemoryEventStore()
+		dispatcher := newGatedDispatcher()
+
+		opts = append([]flux.RelayOption{flux.WithBackOff(&backoff.StopBackOff{})}, opts...)
+		relay := flux.NewRelay(dispatcher, store, opts...)
 
-	relay.Shutdown()
+		return relay, store, dispatcher
+	}
+
+	start := func(relay *flux.Relay) <-chan error {
+		errc := make(chan error, 1)
+		go func() {
+			errc <- relay.Start(context.Background(), flux.StreamRequest{})
+		}()
+
+		return errc
+	}
+
+	createEvent := func(t *testing.T, store *flux.MemoryEventStore) flux.Event {
+		t.Helper()
+
+		e, err := store.CreateEvent(context.Background(), "topic", "key")
+		require.NoError(t, err)
+
+		return e
+	}
+
+	t.Run("shutdown before start does nothing", func(t *testing.T) {
+		relay, _, _ := setup(t)
+		require.NoError(t, relay.Shutdown(context.Background()))
+	})
+
+	t.Run("start while running fails", func(t *testing.T) {
+		relay, store, dispatcher := setup(t)
+		createEvent(t, store)
+
+		errc := start(relay)
+		<-dispatcher.dispatching
+
+		err := relay.Start(context.Background(), flux.StreamRequest{})
+		require.ErrorIs(t, err, flux.ErrRelayRunning)
+
+		dispatcher.release <- struct{}{}
+		require.NoError(t, relay.Shutdown(context.Background()))
+		require.NoError(t, <-errc)
+	})
+
+	t.Run("shutdown waits for in-flight dispatches", func(t *testing.T) {
+		relay, store, dispatcher := setup(t)
+		e := createEvent(t, store)
+
+		errc := start(relay)
+		require.Equal(t, e.ID(), (<-dispatcher.dispatching).ID())
+
+		shutdown := make(chan error, 1)
+		go func() {
+			shutdown <- relay.Shutdown(context.Background())
+		}()
+
+		select {
+		case <-shutdown:
+			require.Fail(t, "shutdown returned before the dispatch finished")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		dispatcher.release <- struct{}{}
+		require.NoError(t, <-shutdown)
+
+		// The relay has already stopped by the time Shutdown returns.
+		select {
+		case err := <-errc:
+			require.NoError(t, err)
+		default:
+			require.Fail(t, "relay was still running after shutdown")
+		}
+	})
+
+	t.Run("shutdown gives up when the context expires", func(t *testing.T) {
+		relay, store, dispatcher := setup(t)
+		createEvent(t, store)
+
+		errc := start(relay)
+		<-dispatcher.dispatching
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		require.ErrorIs(t, relay.Shutdown(ctx), context.DeadlineExceeded)
+
+		// The relay keeps shutting down in the background.
+		dispatcher.release <- struct{}{}
+		require.NoError(t, <-errc)
+	})
+
+	t.Run("shutdown interrupts a retry", func(t *testing.T) {
+		failing := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+			return errors.New("test error")
+		})
+
+		store := flux.NewMemoryEventStore()
+		createEvent(t, store)
+
+		relay := flux.NewRelay(failing, store, flux.WithBackOff(backoff.NewConstantBackOff(time.Hour)))
+		errc := start(relay)
+
+		// Wait for the relay to start retrying.
+		require.Eventually(t, func() bool {
+			err := relay.Start(context.Background(), flux.StreamRequest{})
+			return errors.Is(err, flux.ErrRelayRunning)
+		}, time.Second, time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		require.NoError(t, relay.Shutdown(ctx))
+		require.ErrorContains(t, <-errc, "test error")
+	})
+
+	t.Run("stopped relay can be restarted", func(t *testing.T) {
+		cursors := flux.NewMemoryCursorStore()
+		relay, store, dispatcher := setup(t, flux.WithCursor(cursors, "test"), flux.WithPollInterval(time.Hour))
+
+		for i := 0; i < 3; i++ {
+			e := createEvent(t, store)
+
+			errc := start(relay)
+			require.Equal(t, e.ID(), (<-dispatcher.dispatching).ID())
+			dispatcher.release <- struct{}{}
+
+			require.NoError(t, relay.Shutdown(context.Background()))
+			require.NoError(t, <-errc)
+		}
+
+		// Every run resumed from the cursor, so no event was dispatched twice.
+		require.Empty(t, dispatcher.dispatching)
+	})
+
+	t.Run("concurrent shutdowns", func(t *testing.T) {
+		relay, store, dispatcher := setup(t)
+		createEvent(t, store)
+
+		errc := start(relay)
+		<-dispatcher.dispatching
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				require.NoError(t, relay.Shutdown(context.Background()))
+			}()
+		}
+
+		dispatcher.release <- struct{}{}
+		wg.Wait()
+
+		require.NoError(t, <-errc)
+	})
 }
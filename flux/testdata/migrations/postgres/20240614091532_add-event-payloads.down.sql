@@ -0,0 +1,3 @@
+alter table "events"
+    drop column if exists "payload",
+    drop column if exists "content_type";
\ No newline at end of file
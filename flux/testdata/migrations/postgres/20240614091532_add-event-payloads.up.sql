@@ -0,0 +1,3 @@
+alter table "events"
+    add column "payload" bytea,
+    add column "content_type" varchar(255) not null default '';
\ No newline at end of file
@@ -0,0 +1,14 @@
+create table if not exists "dead_letters" (
+    "event_id" uuid not null,
+    "topic" varchar(64) not null,
+    "sequence" integer not null,
+    "key" varchar(64) not null,
+    "timestamp" timestamp not null,
+    "payload" bytea,
+    "content_type" varchar(255) not null default '',
+    "cause" text not null,
+    "attempts" integer not null,
+    "dead_lettered_at" timestamp not null,
+
+    primary key (event_id)
+);
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists "leases";
\ No newline at end of file
@@ -0,0 +1,7 @@
+create table if not exists "leases" (
+    "name" varchar(255) not null,
+    "holder" varchar(255) not null,
+    "expires_at" timestamptz not null,
+
+    primary key (name)
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+alter table "events"
+    drop column if exists "trace_parent";
\ No newline at end of file
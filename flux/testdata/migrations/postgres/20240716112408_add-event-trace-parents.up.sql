@@ -0,0 +1,2 @@
+alter table "events"
+    add column "trace_parent" varchar(55) not null default '';
\ No newline at end of file
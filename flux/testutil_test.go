@@ -11,11 +11,14 @@ import (
 )
 
 type testEvent struct {
-	id        string
-	topic     flux.EventTopic
-	sequence  uint
-	key       string
-	timestamp time.Time
+	id          string
+	topic       flux.EventTopic
+	sequence    uint
+	key         string
+	timestamp   time.Time
+	payload     []byte
+	contentType string
+	traceParent string
 }
 
 func (event *testEvent) ID() string             { return event.id }
@@ -23,6 +26,9 @@ func (event *testEvent) Topic() flux.EventTopic { return event.topic }
 func (event *testEvent) Sequence() uint         { return event.sequence }
 func (event *testEvent) Key() string            { return event.key }
 func (event *testEvent) Timestamp() time.Time   { return event.timestamp }
+func (event *testEvent) Payload() []byte        { return event.payload }
+func (event *testEvent) ContentType() string    { return event.contentType }
+func (event *testEvent) TraceParent() string    { return event.traceParent }
 
 type eventGenerator struct {
 	t   *testing.T
@@ -44,6 +50,11 @@ func (g *eventGenerator) generateEvent(topic, key string) *testEvent {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	return g.nextEvent(topic, key)
+}
+
+// nextEvent returns a new event with the next sequence. It must be called with mu held.
+func (g *eventGenerator) nextEvent(topic, key string) *testEvent {
 	g.seq++
 	return &testEvent{
 		id:        uuid.NewString(),
@@ -76,8 +87,108 @@ func (g *eventGenerator) NextEvents(ctx context.Context, from, batchSize uint, s
 	defer g.mu.Unlock()
 
 	for i := 0; i < int(batchSize); i++ {
-		g.events = append(g.events, g.generateRandomEvent())
+		g.events = append(g.events, g.nextEvent(uuid.NewString(), uuid.NewString()))
 	}
 
 	return g.events[from : from+batchSize], nil
 }
+
+// staticEventReader is an EventReader which serves a fixed list of events, ordered by sequence.
+type staticEventReader struct {
+	events []flux.Event
+
+	// idle is closed the first time the reader runs out of events.
+	initIdle, closeIdle sync.Once
+	idle                chan struct{}
+}
+
+// Idle returns a channel which is closed once the reader has run out of events.
+func (r *staticEventReader) Idle() <-chan struct{} {
+	r.initIdle.Do(func() { r.idle = make(chan struct{}) })
+	return r.idle
+}
+
+func (r *staticEventReader) Head(ctx context.Context) (flux.Event, error) {
+	if len(r.events) == 0 {
+		return nil, flux.ErrEventNotFound
+	}
+
+	return r.events[len(r.events)-1], nil
+}
+
+func (r *staticEventReader) NextEvents(ctx context.Context, from, batchSize uint, streamLag time.Duration) ([]flux.Event, error) {
+	var events []flux.Event
+	for _, e := range r.events {
+		if uint(len(events)) == batchSize {
+			break
+		}
+
+		if e.Sequence() > from && e.Timestamp().Before(time.Now().Add(-streamLag)) {
+			events = append(events, e)
+		}
+	}
+
+	if len(events) == 0 {
+		r.Idle()
+		r.closeIdle.Do(func() { close(r.idle) })
+
+		return nil, flux.ErrEventNotFound
+	}
+
+	return events, nil
+}
+
+// runRelay starts the relay, and shuts it down once it has streamed all of the reader's events. It returns the error
+// returned by Start.
+func runRelay(t *testing.T, relay *flux.Relay, reader *staticEventReader, req flux.StreamRequest) error {
+	t.Helper()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- relay.Start(context.Background(), req)
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-reader.Idle():
+		if err := relay.Shutdown(context.Background()); err != nil {
+			return err
+		}
+
+		return <-errc
+	}
+}
+
+// runConsumer starts the consumer, and shuts it down once it has handled all of the reader's events. It returns the
+// error returned by Start.
+func runConsumer(t *testing.T, consumer *flux.Consumer, reader *staticEventReader) error {
+	t.Helper()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- consumer.Start(context.Background())
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-reader.Idle():
+		_ = consumer.Shutdown(context.Background())
+		return <-errc
+	}
+}
+
+type testCursor struct {
+	id        string
+	name      string
+	sequence  uint
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+func (cursor *testCursor) ID() string           { return cursor.id }
+func (cursor *testCursor) Name() string         { return cursor.name }
+func (cursor *testCursor) Sequence() uint       { return cursor.sequence }
+func (cursor *testCursor) CreatedAt() time.Time { return cursor.createdAt }
+func (cursor *testCursor) UpdatedAt() time.Time { return cursor.updatedAt }
@@ -0,0 +1,83 @@
+package flux
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracerName is the name of the tracer used by a Relay to trace the events it dispatches.
+const TracerName = "github.com/nickcorin/toolkit/flux"
+
+// traceContext propagates spans using the W3C traceparent and tracestate headers.
+var traceContext = propagation.TraceContext{}
+
+// traceParentFromContext returns the W3C traceparent of the span in the context, or an empty string if the context does
+// not hold a valid span.
+func traceParentFromContext(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+
+	return carrier.Get("traceparent")
+}
+
+// traceParent returns the traceparent which is stored with an event created using the config, which is taken from the
+// context unless it has been set explicitly.
+func (config *EventConfig) traceParent(ctx context.Context) string {
+	if config.TraceParent != "" {
+		return config.TraceParent
+	}
+
+	return traceParentFromContext(ctx)
+}
+
+// contextWithTraceParent returns a copy of the context which holds the span described by the W3C traceparent as a
+// remote span. The context is returned unchanged if the traceparent is empty or invalid.
+func contextWithTraceParent(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+
+	return traceContext.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+}
+
+// injectTraceContext passes the W3C trace context headers of the span in the context to set, so that the span can be
+// continued by the receiver of a message.
+func injectTraceContext(ctx context.Context, set func(key, value string)) {
+	carrier := propagation.MapCarrier{}
+	traceContext.Inject(ctx, carrier)
+
+	for key, value := range carrier {
+		set(key, value)
+	}
+}
+
+// startDispatchSpan starts the span in which an event is dispatched. The span is a child of the span in which the event
+// was created, if there was one.
+func startDispatchSpan(ctx context.Context, tracer trace.Tracer, e Event) (context.Context, trace.Span) {
+	ctx = contextWithTraceParent(ctx, e.TraceParent())
+
+	return tracer.Start(ctx, e.Topic().String()+" publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "flux"),
+			attribute.String("messaging.destination.name", e.Topic().String()),
+			attribute.String("messaging.message.id", e.ID()),
+			attribute.Int64("flux.event.sequence", int64(e.Sequence())),
+			attribute.String("flux.event.key", e.Key()),
+		),
+	)
+}
+
+// endDispatchSpan records the outcome of a dispatch on its span, and ends it.
+func endDispatchSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
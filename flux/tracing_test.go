@@ -0,0 +1,240 @@
+package flux_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/nickcorin/toolkit/flux/fluxpb"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupTracing(t *testing.T) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+	})
+
+	return provider, exporter
+}
+
+// traceParent formats a span context as a W3C traceparent.
+func traceParent(sc trace.SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
+// dispatchSpans returns the exported dispatch spans of the event with the given ID.
+func dispatchSpans(exporter *tracetest.InMemoryExporter, id string) []tracetest.SpanStub {
+	var spans []tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		for _, attr := range span.Attributes {
+			if attr.Key == "messaging.message.id" && attr.Value.AsString() == id {
+				spans = append(spans, span)
+			}
+		}
+	}
+
+	return spans
+}
+
+func TestRelay_Tracing(t *testing.T) {
+	provider, exporter := setupTracing(t)
+	store := flux.NewMemoryEventStore()
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "create")
+	traced, err := store.CreateEvent(ctx, "test-topic", "traced")
+	require.NoError(t, err)
+	parent.End()
+
+	untraced, err := store.CreateEvent(context.Background(), "test-topic", "untraced")
+	require.NoError(t, err)
+
+	failing, err := store.CreateEvent(ctx, "test-topic", "failing")
+	require.NoError(t, err)
+
+	t.Run("trace parent is captured from the context", func(t *testing.T) {
+		require.Equal(t, traceParent(parent.SpanContext()), traced.TraceParent())
+		require.Empty(t, untraced.TraceParent())
+	})
+
+	var (
+		mu       sync.Mutex
+		failed   bool
+		contexts = make(map[string][]trace.SpanContext)
+	)
+
+	dispatcher := flux.DispatcherFunc(func(ctx context.Context, e flux.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+
+		contexts[e.ID()] = append(contexts[e.ID()], trace.SpanContextFromContext(ctx))
+
+		if e.ID() == failing.ID() && !failed {
+			failed = true
+			return errors.New("test error")
+		}
+
+		return nil
+	})
+
+	reader := &staticEventReader{events: []flux.Event{traced, untraced, failing}}
+	relay := flux.NewRelay(dispatcher, reader,
+		flux.WithTracerProvider(provider),
+		flux.WithBackOff(backoff.NewConstantBackOff(10*time.Millisecond)),
+	)
+	require.NoError(t, runRelay(t, relay, reader, flux.StreamRequest{}))
+
+	t.Run("dispatch span is a child of the creation span", func(t *testing.T) {
+		spans := dispatchSpans(exporter, traced.ID())
+		require.Len(t, spans, 1)
+
+		span := spans[0]
+		require.Equal(t, "test-topic publish", span.Name)
+		require.Equal(t, trace.SpanKindProducer, span.SpanKind)
+		require.Equal(t, parent.SpanContext().TraceID(), span.SpanContext.TraceID())
+		require.Equal(t, parent.SpanContext().SpanID(), span.Parent.SpanID())
+		require.Contains(t, span.Attributes, attribute.String("flux.event.key", "traced"))
+		require.Contains(t, span.Attributes, attribute.Int64("flux.event.sequence", int64(traced.Sequence())))
+
+		// The dispatcher is called within the dispatch span.
+		require.Equal(t, []trace.SpanContext{span.SpanContext}, contexts[traced.ID()])
+	})
+
+	t.Run("dispatch span of an untraced event is a root span", func(t *testing.T) {
+		spans := dispatchSpans(exporter, untraced.ID())
+		require.Len(t, spans, 1)
+		require.False(t, spans[0].Parent.IsValid())
+	})
+
+	t.Run("failed dispatches are recorded", func(t *testing.T) {
+		spans := dispatchSpans(exporter, failing.ID())
+		require.Len(t, spans, 2)
+
+		require.Equal(t, codes.Error, spans[0].Status.Code)
+		require.Equal(t, "test error", spans[0].Status.Description)
+		require.Len(t, spans[0].Events, 1)
+		require.Equal(t, "exception", spans[0].Events[0].Name)
+
+		require.Equal(t, codes.Unset, spans[1].Status.Code)
+		require.Equal(t, parent.SpanContext().SpanID(), spans[1].Parent.SpanID())
+	})
+}
+
+func TestDispatcher_TraceContext(t *testing.T) {
+	provider, exporter := setupTracing(t)
+	codec := flux.NewJSONCodec()
+	generator := NewEventGenerator(t)
+
+	ctx, span := provider.Tracer("test").Start(context.Background(), "dispatch")
+	defer span.End()
+
+	expected := traceParent(span.SpanContext())
+
+	t.Run("webhook", func(t *testing.T) {
+		received := make(chan trace.SpanContext, 1)
+		receiver := flux.NewWebhookReceiver(codec, []byte("secret"), func(ctx context.Context, e flux.Event) error {
+			received <- trace.SpanContextFromContext(ctx)
+			return nil
+		})
+
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := flux.NewWebhookDispatcher(server.URL, codec, []byte("secret"))
+		require.NoError(t, dispatcher.Dispatch(ctx, generator.generateRandomEvent()))
+
+		sc := <-received
+		require.True(t, sc.IsRemote())
+		require.Equal(t, expected, traceParent(sc))
+	})
+
+	t.Run("nats", func(t *testing.T) {
+		conn := setupNats(t)
+
+		sub, err := conn.SubscribeSync("test-topic")
+		require.NoError(t, err)
+
+		dispatcher := flux.NewNatsDispatcher(conn, codec)
+		require.NoError(t, dispatcher.Dispatch(ctx, generator.generateEvent("test-topic", "test-key")))
+
+		msg, err := sub.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+		require.Equal(t, expected, msg.Header.Get("traceparent"))
+	})
+
+	t.Run("jetstream", func(t *testing.T) {
+		conn := setupNats(t)
+
+		js, err := jetstream.New(conn)
+		require.NoError(t, err)
+
+		_, err = js.CreateStream(context.Background(), jetstream.StreamConfig{
+			Name:     "EVENTS",
+			Subjects: []string{"events.>"},
+		})
+		require.NoError(t, err)
+
+		sub, err := conn.SubscribeSync("events.test-topic")
+		require.NoError(t, err)
+
+		dispatcher, err := flux.NewJetStreamDispatcher(js, codec, flux.WithSubjectTemplate("events.{{ .Topic }}"))
+		require.NoError(t, err)
+		require.NoError(t, dispatcher.Dispatch(ctx, generator.generateEvent("test-topic", "test-key")))
+
+		msg, err := sub.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+		require.Equal(t, expected, msg.Header.Get("traceparent"))
+	})
+
+	t.Run("kafka", func(t *testing.T) {
+		producer, consumer := setupKafka(t, 1, "test-topic")
+
+		dispatcher := flux.NewKafkaDispatcher(producer, codec)
+		require.NoError(t, dispatcher.Dispatch(ctx, generator.generateEvent("test-topic", "test-key")))
+
+		records := pollRecords(t, consumer, 1)
+		require.Contains(t, records[0].Headers, kgo.RecordHeader{Key: "traceparent", Value: []byte(expected)})
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		e := generator.generateEvent("test-topic", "test-key")
+		e.traceParent = expected
+
+		client := setupGRPCClient(t, &staticEventReader{events: []flux.Event{e}}, flux.WithTracerProvider(provider))
+
+		streamCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.Stream(streamCtx, &fluxpb.StreamRequest{StartSequence: uint64(e.Sequence() - 1)})
+		require.NoError(t, err)
+
+		got, err := stream.Recv()
+		require.NoError(t, err)
+
+		// The event carries the trace context of its dispatch span, in place of the one it was created with.
+		var dispatch []tracetest.SpanStub
+		require.Eventually(t, func() bool {
+			dispatch = dispatchSpans(exporter, e.ID())
+			return len(dispatch) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+
+		require.Equal(t, traceParent(dispatch[0].SpanContext), got.TraceParent())
+		require.Equal(t, span.SpanContext().SpanID(), dispatch[0].Parent.SpanID())
+	})
+}
@@ -0,0 +1,525 @@
+package flux
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Headers which are set on every request sent by a WebhookDispatcher.
+const (
+	WebhookHeaderEventID   = "Flux-Event-Id"
+	WebhookHeaderSignature = "Flux-Signature"
+	WebhookHeaderTimestamp = "Flux-Timestamp"
+)
+
+// webhookSignaturePrefix identifies the algorithm used to create a webhook signature.
+const webhookSignaturePrefix = "sha256="
+
+// webhookDeadLetterHeaderPrefix is the lowercase prefix of the headers which carry the details of a DeadLetter.
+const webhookDeadLetterHeaderPrefix = "flux-dead-letter-"
+
+var (
+	// ErrInvalidSignature is an error that is returned when a webhook request is not signed, or is signed with a
+	// different secret.
+	ErrInvalidSignature = errors.New("invalid signature")
+
+	// ErrSignatureExpired is an error that is returned when a webhook request was signed too long ago.
+	ErrSignatureExpired = errors.New("signature expired")
+)
+
+// Compile-time assertion that WebhookDispatcher implements the Dispatcher interface.
+var _ Dispatcher = (*WebhookDispatcher)(nil)
+
+// NewWebhookDispatcher returns a new webhook dispatcher which posts events to the given URL. The URL may be empty if
+// every topic is given its own URL using WithTopicURL.
+func NewWebhookDispatcher(url string, codec Codec, secret []byte, opts ...WebhookDispatcherOption) *WebhookDispatcher {
+	config := WebhookDispatcherConfig{
+		Client:        &http.Client{Timeout: 10 * time.Second},
+		TopicURLs:     make(map[EventTopic]string),
+		MaxRetries:    3,
+		RetryInterval: 500 * time.Millisecond,
+		MaxRetryAfter: DefaultWebhookMaxRetryAfter,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &WebhookDispatcher{
+		url:    url,
+		codec:  codec,
+		secret: secret,
+		config: config,
+	}
+}
+
+// WebhookDispatcher is a dispatcher that posts events to HTTP endpoints.
+//
+// The body of each request is the encoded event, which is signed using HMAC-SHA256 along with the headers which
+// describe the event, so that receivers can verify that it was sent by a trusted party. See SignWebhook and
+// WebhookReceiver.
+//
+// Requests are retried on network errors, 429 and 5xx responses, waiting for the duration of a Retry-After header if
+// the endpoint provides one.
+type WebhookDispatcher struct {
+	url    string
+	codec  Codec
+	secret []byte
+	config WebhookDispatcherConfig
+}
+
+type WebhookDispatcherConfig struct {
+	// The HTTP client used to send requests.
+	Client *http.Client
+
+	// URLs to post events with specific topics to, instead of the dispatcher's default URL.
+	TopicURLs map[EventTopic]string
+
+	// The number of times a request is retried before giving up.
+	MaxRetries uint
+
+	// The amount of time to wait before the first retry, which doubles with every subsequent retry.
+	RetryInterval time.Duration
+
+	// The longest the dispatcher waits before a retry when an endpoint asks it to wait using a Retry-After header.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultWebhookMaxRetryAfter is the longest a WebhookDispatcher waits for the duration of a Retry-After header.
+const DefaultWebhookMaxRetryAfter = time.Minute
+
+// WebhookDispatcherOption is an interface that allows for functional options to be applied to a
+// WebhookDispatcherConfig.
+type WebhookDispatcherOption interface {
+	Apply(*WebhookDispatcherConfig)
+}
+
+// WebhookDispatcherOptionFunc is a function type that implements the WebhookDispatcherOption interface.
+type WebhookDispatcherOptionFunc func(*WebhookDispatcherConfig)
+
+// Apply applies the function to the dispatcher.
+func (f WebhookDispatcherOptionFunc) Apply(config *WebhookDispatcherConfig) {
+	f(config)
+}
+
+// WithHTTPClient sets the HTTP client of the dispatcher.
+func WithHTTPClient(client *http.Client) WebhookDispatcherOption {
+	return WebhookDispatcherOptionFunc(func(config *WebhookDispatcherConfig) {
+		config.Client = client
+	})
+}
+
+// WithTopicURL posts events with the given topic to their own URL.
+func WithTopicURL(topic EventTopic, url string) WebhookDispatcherOption {
+	return WebhookDispatcherOptionFunc(func(config *WebhookDispatcherConfig) {
+		config.TopicURLs[topic] = url
+	})
+}
+
+// WithWebhookRetries sets the number of times a request is retried, and the amount of time to wait before the first
+// retry.
+func WithWebhookRetries(maxRetries uint, interval time.Duration) WebhookDispatcherOption {
+	return WebhookDispatcherOptionFunc(func(config *WebhookDispatcherConfig) {
+		config.MaxRetries = maxRetries
+		config.RetryInterval = interval
+	})
+}
+
+// WithMaxRetryAfter sets the longest the dispatcher waits before a retry when an endpoint responds with a Retry-After
+// header, so that a misbehaving endpoint cannot stall the dispatcher.
+func WithMaxRetryAfter(wait time.Duration) WebhookDispatcherOption {
+	return WebhookDispatcherOptionFunc(func(config *WebhookDispatcherConfig) {
+		config.MaxRetryAfter = wait
+	})
+}
+
+// Dispatch posts the event, and blocks until the endpoint has responded with a 2xx status, or the request has been
+// retried too many times.
+func (d *WebhookDispatcher) Dispatch(ctx context.Context, e Event) error {
+	url := d.url
+	if topicURL, ok := d.config.TopicURLs[e.Topic()]; ok {
+		url = topicURL
+	}
+
+	if url == "" {
+		return fmt.Errorf("no url configured for topic %q", e.Topic())
+	}
+
+	// Codecs which set headers of their own, such as the CloudEvents codec, also set the content type.
+	header := http.Header{}
+	if _, ok := d.codec.(HeaderCodec); !ok {
+		header.Set("Content-Type", codecContentType(d.codec))
+	}
+
+	body, err := encodeMessage(d.codec, e, header)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	interval := d.config.RetryInterval
+
+	for attempt := uint(0); ; attempt++ {
+		retryAfter, err := d.post(ctx, url, e, header, body)
+		if err == nil {
+			return nil
+		}
+
+		var retryable *retryableError
+		if !errors.As(err, &retryable) || attempt >= d.config.MaxRetries {
+			return fmt.Errorf("failed to dispatch event: %w", err)
+		}
+
+		wait := interval
+		if retryAfter > 0 {
+			wait = min(retryAfter, d.config.MaxRetryAfter)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("failed to dispatch event: %w", errors.Join(err, ctx.Err()))
+		case <-time.After(wait):
+		}
+
+		interval *= 2
+	}
+}
+
+// post sends a single request. If the request may be retried, it returns a retryableError along with the duration
+// requested by the endpoint's Retry-After header, if any.
+func (d *WebhookDispatcher) post(
+	ctx context.Context,
+	url string,
+	e Event,
+	header http.Header,
+	body []byte,
+) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header = header.Clone()
+	req.Header.Set(WebhookHeaderEventID, e.ID())
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	injectDeadLetter(e, req.Header.Set)
+	injectTraceContext(ctx, req.Header.Set)
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(d.secret, timestamp, req.Header, body))
+
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return 0, &retryableError{err: fmt.Errorf("send request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return 0, nil
+	}
+
+	err = fmt.Errorf("unexpected status: %s", resp.Status)
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusServiceUnavailable:
+		return parseRetryAfter(resp.Header.Get("Retry-After")), &retryableError{err: err}
+	case resp.StatusCode >= 500:
+		return 0, &retryableError{err: err}
+	default:
+		return 0, err
+	}
+}
+
+// retryableError wraps an error which may succeed if the request is sent again.
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number of seconds or an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+
+	return 0
+}
+
+// codecContentType returns the media type of the data produced by a codec.
+func codecContentType(codec Codec) string {
+	switch codec.(type) {
+	case *JSONCodec:
+		return "application/json"
+	case *ProtobufCodec:
+		return "application/x-protobuf"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// SignWebhook returns the signature of a webhook request, sent at the given unix timestamp.
+//
+// The signature covers the body of the request, along with its Content-Type header, the ce-* headers which carry the
+// attributes of a CloudEvent in binary mode and the headers which carry the details of a DeadLetter, so that none of
+// the event's attributes can be tampered with. Other headers, such as the W3C trace context, are not signed.
+func SignWebhook(secret []byte, timestamp string, header http.Header, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(canonicalWebhookHeaders(header)))
+	mac.Write([]byte("\n"))
+	mac.Write(body)
+
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// canonicalWebhookHeaders returns the signed headers as "name:value" lines ordered by name, with lowercase names and
+// the values of repeated headers joined by commas. Header values cannot contain newlines, so the lines are unambiguous.
+func canonicalWebhookHeaders(header http.Header) string {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		lower := strings.ToLower(name)
+		if lower == cloudEventsContentType || strings.HasPrefix(lower, cloudEventsHeaderPrefix) ||
+			strings.HasPrefix(lower, webhookDeadLetterHeaderPrefix) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool { return strings.ToLower(names[i]) < strings.ToLower(names[j]) })
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(strings.ToLower(name))
+		b.WriteString(":")
+		b.WriteString(strings.Join(header[name], ","))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// VerifyWebhook checks that a webhook request was signed with the given secret, no longer than tolerance ago. A
+// tolerance of zero disables the check on the timestamp.
+func VerifyWebhook(
+	secret []byte,
+	timestamp string,
+	header http.Header,
+	body []byte,
+	signature string,
+	tolerance time.Duration,
+) error {
+	if !strings.HasPrefix(signature, webhookSignaturePrefix) {
+		return ErrInvalidSignature
+	}
+
+	if !hmac.Equal([]byte(SignWebhook(secret, timestamp, header, body)), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	if tolerance == 0 {
+		return nil
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+// DefaultWebhookTolerance is the maximum age of a webhook request accepted by a WebhookReceiver.
+const DefaultWebhookTolerance = 5 * time.Minute
+
+// DefaultWebhookMaxBodySize is the size of the largest request body accepted by a WebhookReceiver.
+const DefaultWebhookMaxBodySize = 1 << 20
+
+// NewWebhookReceiver returns a new webhook receiver, which passes the events it receives to the given handler.
+func NewWebhookReceiver(
+	codec Codec,
+	secret []byte,
+	handler ConsumerFunc,
+	opts ...WebhookReceiverOption,
+) *WebhookReceiver {
+	config := WebhookReceiverConfig{
+		MaxBodySize: DefaultWebhookMaxBodySize,
+		Tolerance:   DefaultWebhookTolerance,
+	}
+
+	for _, opt := range opts {
+		opt.Apply(&config)
+	}
+
+	return &WebhookReceiver{
+		codec:   codec,
+		secret:  secret,
+		handler: handler,
+		config:  config,
+	}
+}
+
+type WebhookReceiverConfig struct {
+	// The size of the largest request body the receiver reads, in bytes. Larger requests are rejected.
+	MaxBodySize int64
+
+	// The maximum age of a request accepted by the receiver, which guards against replayed requests. A tolerance of
+	// zero disables the check.
+	Tolerance time.Duration
+
+	// The logger which records errors returned by the handler. If nil, slog.Default() is used.
+	Logger *slog.Logger
+}
+
+// WebhookReceiverOption is an interface that allows for functional options to be applied to a WebhookReceiverConfig.
+type WebhookReceiverOption interface {
+	Apply(*WebhookReceiverConfig)
+}
+
+// WebhookReceiverOptionFunc is a function type that implements the WebhookReceiverOption interface.
+type WebhookReceiverOptionFunc func(*WebhookReceiverConfig)
+
+// Apply applies the function to the receiver.
+func (f WebhookReceiverOptionFunc) Apply(config *WebhookReceiverConfig) {
+	f(config)
+}
+
+// WithMaxBodySize sets the size of the largest request body the receiver reads, in bytes.
+func WithMaxBodySize(size int64) WebhookReceiverOption {
+	return WebhookReceiverOptionFunc(func(config *WebhookReceiverConfig) {
+		if size > 0 {
+			config.MaxBodySize = size
+		}
+	})
+}
+
+// WithTolerance sets the maximum age of a request accepted by the receiver. A tolerance of zero disables the check,
+// leaving the receiver open to replayed requests.
+func WithTolerance(tolerance time.Duration) WebhookReceiverOption {
+	return WebhookReceiverOptionFunc(func(config *WebhookReceiverConfig) {
+		config.Tolerance = tolerance
+	})
+}
+
+// WithReceiverLogger sets the logger which records errors returned by the receiver's handler, in place of
+// slog.Default().
+func WithReceiverLogger(logger *slog.Logger) WebhookReceiverOption {
+	return WebhookReceiverOptionFunc(func(config *WebhookReceiverConfig) {
+		config.Logger = logger
+	})
+}
+
+// Compile-time assertion that WebhookReceiver implements the http.Handler interface.
+var _ http.Handler = (*WebhookReceiver)(nil)
+
+// WebhookReceiver is an http.Handler which receives events sent by a WebhookDispatcher.
+//
+// Requests with an invalid signature, or which were signed longer ago than the receiver's Tolerance, are rejected with
+// a 401, and requests whose body is larger than the receiver's MaxBodySize are rejected with a 413. The handler is
+// called with the trace context of the request, and if it returns an error, the error is logged and the receiver
+// responds with a 503 so that the dispatcher retries the request. The error itself is not sent to the dispatcher.
+type WebhookReceiver struct {
+	codec   Codec
+	secret  []byte
+	handler ConsumerFunc
+	config  WebhookReceiverConfig
+}
+
+// Verify checks the signature of a webhook request, and decodes the event in its body. The event is a *DeadLetter if
+// the request carries its details. It returns an *http.MaxBytesError if the body is larger than the receiver's
+// MaxBodySize.
+func (r *WebhookReceiver) Verify(req *http.Request) (Event, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, r.config.MaxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	err = VerifyWebhook(
+		r.secret,
+		req.Header.Get(WebhookHeaderTimestamp),
+		req.Header,
+		body,
+		req.Header.Get(WebhookHeaderSignature),
+		r.config.Tolerance,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := decodeMessage(r.codec, req.Header, body)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractDeadLetter(e, req.Header.Get)
+}
+
+func (r *WebhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	e, err := r.Verify(req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrSignatureExpired) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Continue the trace of the dispatch which sent the request, if any.
+	ctx := traceContext.Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+	if err := r.handler(ctx, e); err != nil {
+		logger := r.config.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+
+		logger.ErrorContext(ctx, "failed to handle webhook", eventAttrs(e), "error", err)
+
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
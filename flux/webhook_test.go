@@ -0,0 +1,403 @@
+package flux_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nickcorin/toolkit/flux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookDispatcher(t *testing.T) {
+	var (
+		secret    = []byte("test-secret")
+		codec     = flux.NewJSONCodec()
+		generator = NewEventGenerator(t)
+	)
+
+	t.Run("receiver decodes dispatched events", func(t *testing.T) {
+		received := make(chan flux.Event, 1)
+		receiver := flux.NewWebhookReceiver(codec, secret, func(ctx context.Context, e flux.Event) error {
+			received <- e
+			return nil
+		})
+
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		e := generator.generateEvent("test-topic", "test-key")
+		e.payload = []byte("test")
+		e.contentType = "text/plain"
+
+		dispatcher := flux.NewWebhookDispatcher(server.URL, codec, secret)
+		require.NoError(t, dispatcher.Dispatch(context.Background(), e))
+
+		got := <-received
+		require.Equal(t, e.ID(), got.ID())
+		require.Equal(t, e.Topic(), got.Topic())
+		require.Equal(t, e.Sequence(), got.Sequence())
+		require.Equal(t, e.Payload(), got.Payload())
+	})
+
+	t.Run("receiver rejects a different secret", func(t *testing.T) {
+		receiver := flux.NewWebhookReceiver(codec, secret, func(ctx context.Context, e flux.Event) error {
+			t.Error("handler should not be called")
+			return nil
+		})
+
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := flux.NewWebhookDispatcher(server.URL, codec, []byte("other-secret"))
+		err := dispatcher.Dispatch(context.Background(), generator.generateRandomEvent())
+		require.ErrorContains(t, err, strconv.Itoa(http.StatusUnauthorized))
+	})
+
+	t.Run("topic urls", func(t *testing.T) {
+		var defaultHits, topicHits atomic.Int32
+
+		defaultServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defaultHits.Add(1)
+		}))
+		defer defaultServer.Close()
+
+		topicServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			topicHits.Add(1)
+		}))
+		defer topicServer.Close()
+
+		dispatcher := flux.NewWebhookDispatcher(
+			defaultServer.URL,
+			codec,
+			secret,
+			flux.WithTopicURL("special", topicServer.URL),
+		)
+
+		require.NoError(t, dispatcher.Dispatch(context.Background(), generator.generateEvent("special", "key")))
+		require.NoError(t, dispatcher.Dispatch(context.Background(), generator.generateEvent("other", "key")))
+
+		require.EqualValues(t, 1, defaultHits.Load())
+		require.EqualValues(t, 1, topicHits.Load())
+	})
+
+	t.Run("missing url", func(t *testing.T) {
+		dispatcher := flux.NewWebhookDispatcher("", codec, secret)
+		require.Error(t, dispatcher.Dispatch(context.Background(), generator.generateRandomEvent()))
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			status   int
+			attempts int32
+			err      bool
+		}{
+			{name: "server error", status: http.StatusInternalServerError, attempts: 3},
+			{name: "too many requests", status: http.StatusTooManyRequests, attempts: 3},
+			{name: "service unavailable", status: http.StatusServiceUnavailable, attempts: 3},
+			{name: "bad request", status: http.StatusBadRequest, attempts: 1, err: true},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var attempts atomic.Int32
+
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					// Fail the first two attempts.
+					if attempts.Add(1) > 2 {
+						return
+					}
+
+					w.WriteHeader(tt.status)
+				}))
+				defer server.Close()
+
+				dispatcher := flux.NewWebhookDispatcher(
+					server.URL,
+					codec,
+					secret,
+					flux.WithWebhookRetries(3, time.Millisecond),
+				)
+
+				err := dispatcher.Dispatch(context.Background(), generator.generateRandomEvent())
+				if tt.err {
+					require.Error(t, err)
+				} else {
+					require.NoError(t, err)
+				}
+
+				require.Equal(t, tt.attempts, attempts.Load())
+			})
+		}
+	})
+
+	t.Run("honours retry after", func(t *testing.T) {
+		var attempts atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.Header().Set("Retry-After", "1")
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}))
+		defer server.Close()
+
+		dispatcher := flux.NewWebhookDispatcher(
+			server.URL,
+			codec,
+			secret,
+			flux.WithWebhookRetries(1, time.Millisecond),
+		)
+
+		start := time.Now()
+		require.NoError(t, dispatcher.Dispatch(context.Background(), generator.generateRandomEvent()))
+		require.GreaterOrEqual(t, time.Since(start), time.Second)
+	})
+
+	t.Run("limits retry after", func(t *testing.T) {
+		var attempts atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if attempts.Add(1) == 1 {
+				w.Header().Set("Retry-After", "3600")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}))
+		defer server.Close()
+
+		dispatcher := flux.NewWebhookDispatcher(
+			server.URL,
+			codec,
+			secret,
+			flux.WithWebhookRetries(1, time.Millisecond),
+			flux.WithMaxRetryAfter(10*time.Millisecond),
+		)
+
+		start := time.Now()
+		require.NoError(t, dispatcher.Dispatch(context.Background(), generator.generateRandomEvent()))
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("receiver rejects large bodies", func(t *testing.T) {
+		receiver := flux.NewWebhookReceiver(
+			codec,
+			secret,
+			func(ctx context.Context, e flux.Event) error {
+				t.Error("handler should not be called")
+				return nil
+			},
+			flux.WithMaxBodySize(16),
+		)
+
+		server := httptest.NewServer(receiver)
+		defer server.Close()
+
+		dispatcher := flux.NewWebhookDispatcher(server.URL, codec, secret)
+		err := dispatcher.Dispatch(context.Background(), generator.generateRandomEvent())
+		require.ErrorContains(t, err, strconv.Itoa(http.StatusRequestEntityTooLarge))
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		var attempts atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempts.Add(1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		dispatcher := flux.NewWebhookDispatcher(
+			server.URL,
+			codec,
+			secret,
+			flux.WithWebhookRetries(2, time.Millisecond),
+		)
+
+		require.Error(t, dispatcher.Dispatch(context.Background(), generator.generateRandomEvent()))
+		require.EqualValues(t, 3, attempts.Load())
+	})
+}
+
+func TestWebhookReceiver_HandlerError(t *testing.T) {
+	secret := []byte("test-secret")
+
+	var (
+		mu   sync.Mutex
+		logs bytes.Buffer
+		body string
+	)
+
+	receiver := flux.NewWebhookReceiver(
+		flux.NewJSONCodec(),
+		secret,
+		func(ctx context.Context, e flux.Event) error {
+			return errors.New("handler failed")
+		},
+		flux.WithReceiverLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		rec := httptest.NewRecorder()
+		receiver.ServeHTTP(rec, r)
+		body = rec.Body.String()
+
+		w.WriteHeader(rec.Code)
+	}))
+	defer server.Close()
+
+	dispatcher := flux.NewWebhookDispatcher(
+		server.URL,
+		flux.NewJSONCodec(),
+		secret,
+		flux.WithWebhookRetries(0, 0),
+	)
+
+	err := dispatcher.Dispatch(context.Background(), NewEventGenerator(t).generateRandomEvent())
+	require.ErrorContains(t, err, strconv.Itoa(http.StatusServiceUnavailable))
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// The handler's error is logged, rather than sent to the dispatcher.
+	require.NotContains(t, body, "handler failed")
+	require.Contains(t, logs.String(), "handler failed")
+}
+
+func TestWebhookReceiver_Tolerance(t *testing.T) {
+	var (
+		secret    = []byte("test-secret")
+		codec     = flux.NewJSONCodec()
+		timestamp = strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	)
+
+	body, err := codec.Encode(NewEventGenerator(t).generateRandomEvent())
+	require.NoError(t, err)
+
+	serve := func(opts ...flux.WebhookReceiverOption) int {
+		receiver := flux.NewWebhookReceiver(
+			codec,
+			secret,
+			func(ctx context.Context, e flux.Event) error { return nil },
+			opts...,
+		)
+
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		req.Header.Set(flux.WebhookHeaderTimestamp, timestamp)
+		req.Header.Set(flux.WebhookHeaderSignature, flux.SignWebhook(secret, timestamp, req.Header, body))
+
+		rec := httptest.NewRecorder()
+		receiver.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	// The request was signed an hour ago, which is longer ago than the default tolerance.
+	require.Equal(t, http.StatusUnauthorized, serve())
+	require.Equal(t, http.StatusUnauthorized, serve(flux.WithTolerance(time.Minute)))
+
+	require.Equal(t, http.StatusNoContent, serve(flux.WithTolerance(2*time.Hour)))
+	require.Equal(t, http.StatusNoContent, serve(flux.WithTolerance(0)))
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	var (
+		secret = []byte("test-secret")
+		body   = []byte("test-body")
+		now    = strconv.FormatInt(time.Now().Unix(), 10)
+		old    = strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	)
+
+	header := http.Header{}
+	header.Set("Content-Type", "text/plain")
+	header.Set("Ce-Type", "test-topic")
+	header.Set("Ce-Id", "test-id")
+
+	// Headers which are not signed may change in transit.
+	withUnsigned := header.Clone()
+	withUnsigned.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	tampered := func(name, value string) http.Header {
+		h := header.Clone()
+		h.Set(name, value)
+
+		return h
+	}
+
+	tests := []struct {
+		name      string
+		timestamp string
+		header    http.Header
+		signature string
+		err       error
+	}{
+		{name: "valid", timestamp: now, header: header, signature: flux.SignWebhook(secret, now, header, body)},
+		{
+			name:      "unsigned header",
+			timestamp: now,
+			header:    withUnsigned,
+			signature: flux.SignWebhook(secret, now, header, body),
+		},
+		{name: "missing signature", timestamp: now, header: header, signature: "", err: flux.ErrInvalidSignature},
+		{
+			name:      "wrong secret",
+			timestamp: now,
+			header:    header,
+			signature: flux.SignWebhook([]byte("other"), now, header, body),
+			err:       flux.ErrInvalidSignature,
+		},
+		{
+			name:      "tampered timestamp",
+			timestamp: old,
+			header:    header,
+			signature: flux.SignWebhook(secret, now, header, body),
+			err:       flux.ErrInvalidSignature,
+		},
+		{
+			name:      "tampered ce header",
+			timestamp: now,
+			header:    tampered("Ce-Type", "other-topic"),
+			signature: flux.SignWebhook(secret, now, header, body),
+			err:       flux.ErrInvalidSignature,
+		},
+		{
+			name:      "added ce header",
+			timestamp: now,
+			header:    tampered("Ce-Subject", "other-key"),
+			signature: flux.SignWebhook(secret, now, header, body),
+			err:       flux.ErrInvalidSignature,
+		},
+		{
+			name:      "tampered content type",
+			timestamp: now,
+			header:    tampered("Content-Type", "application/json"),
+			signature: flux.SignWebhook(secret, now, header, body),
+			err:       flux.ErrInvalidSignature,
+		},
+		{
+			name:      "expired",
+			timestamp: old,
+			header:    header,
+			signature: flux.SignWebhook(secret, old, header, body),
+			err:       flux.ErrSignatureExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := flux.VerifyWebhook(secret, tt.timestamp, tt.header, body, tt.signature, flux.DefaultWebhookTolerance)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
@@ -20,6 +20,20 @@ var (
 	_ Scannable = (*sql.Rows)(nil)
 )
 
+// Querier is an interface that wraps the query methods shared by *sql.DB, *sql.Tx and *sql.Conn. It allows code to run
+// the same queries either on their own, or as part of a caller's transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Tx)(nil)
+	_ Querier = (*sql.Conn)(nil)
+)
+
 // Config represents the configuration for a database connection.
 type Config struct {
 	Dialect  Dialect `envconfig:"DIALECT"`